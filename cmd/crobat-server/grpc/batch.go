@@ -0,0 +1,158 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/cgboal/sonarsearch/pkg/grpcstatus"
+	"github.com/cgboal/sonarsearch/pkg/search"
+	crobat "github.com/cgboal/sonarsearch/proto"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// batchMethod schedules each query of a batch on its own, rather than the
+// batch as a whole.
+const batchMethod = "/proto.Crobat/Batch"
+
+// batchMethods maps the query types accepted in a batch to the RPC which
+// answers them.
+var batchMethods = map[string]string{
+	"subdomains": "GetSubdomains",
+	"resolve":    "Resolve",
+	"tlds":       "GetTLDs",
+	"suffix":     "GetSuffixDomains",
+	"reverse":    "ReverseDNS",
+	"cname":      "GetCNAMEChain",
+	"aliases":    "GetCNAMEAliases",
+	"ns":         "GetNS",
+	"ns_domains": "GetNSDomains",
+	"mx":         "GetMX",
+	"mx_domains": "GetMXDomains",
+}
+
+// queryStream hands the results of a query run outside of its own RPC to
+// send, and satisfies the server stream of every streaming RPC.
+type queryStream struct {
+	gogrpc.ServerStream
+	ctx  context.Context
+	send func(*crobat.Domain) error
+}
+
+func (qs *queryStream) Context() context.Context {
+	return qs.ctx
+}
+
+func (qs *queryStream) Send(domain *crobat.Domain) error {
+	return qs.send(domain)
+}
+
+// RunQuery runs a single query of the given type, such as subdomains or
+// ns_domains, passing each result to send. It is scheduled in the same way
+// as the RPC which answers that type of query, and fails with a gRPC status.
+func (s *CrobatServer) RunQuery(ctx context.Context, queryType string, query *crobat.QueryRequest, send func(*crobat.Domain) error) error {
+	if queryType == "" {
+		queryType = "subdomains"
+	}
+
+	method, exists := batchMethods[queryType]
+	if !exists {
+		return grpcstatus.FromSearch(&search.QueryError{Reason: "unknown query type " + queryType})
+	}
+
+	release, err := s.Scheduler.Acquire(ctx, methodQueryType(method))
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+	defer release()
+
+	stream := &queryStream{ctx: ctx, send: send}
+	switch method {
+	case "GetSubdomains":
+		return s.GetSubdomains(query, stream)
+	case "Resolve":
+		return s.Resolve(query, stream)
+	case "GetTLDs":
+		return s.GetTLDs(query, stream)
+	case "GetSuffixDomains":
+		return s.GetSuffixDomains(query, stream)
+	case "ReverseDNS":
+		return s.ReverseDNS(query, stream)
+	case "GetCNAMEChain":
+		return s.GetCNAMEChain(query, stream)
+	case "GetCNAMEAliases":
+		return s.GetCNAMEAliases(query, stream)
+	case "GetNS":
+		return s.GetNS(query, stream)
+	case "GetNSDomains":
+		return s.GetNSDomains(query, stream)
+	case "GetMX":
+		return s.GetMX(query, stream)
+	default:
+		return s.GetMXDomains(query, stream)
+	}
+}
+
+func (s *CrobatServer) Batch(stream crobat.Crobat_BatchServer) error {
+	// once a send fails the client has gone, so the rest of the batch is
+	// canceled rather than run for nobody
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	// results of concurrent queries share the stream, which is not safe to
+	// send on from more than one goroutine
+	var sendLock sync.Mutex
+	send := func(result *crobat.BatchResult) error {
+		sendLock.Lock()
+		defer sendLock.Unlock()
+		return stream.Send(result)
+	}
+
+	running := make(chan struct{}, search.BatchConcurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		request, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		select {
+		case running <- struct{}{}:
+		case <-ctx.Done():
+			return status.Error(codes.Canceled, ctx.Err().Error())
+		}
+
+		wg.Add(1)
+		go func(request *crobat.BatchRequest) {
+			defer wg.Done()
+			defer func() { <-running }()
+
+			id := request.Id
+			if id == "" {
+				id = request.Query
+			}
+
+			query := &crobat.QueryRequest{Query: request.Query, Cursor: request.Cursor}
+			err := s.RunQuery(ctx, request.Type, query, func(domain *crobat.Domain) error {
+				return send(&crobat.BatchResult{Id: id, Query: request.Query, Domain: domain})
+			})
+
+			done := &crobat.BatchResult{Id: id, Query: request.Query, Done: true}
+			if err != nil {
+				st := status.Convert(err)
+				done.Error = st.Message()
+				done.Code = st.Code().String()
+			}
+			if err := send(done); err != nil {
+				cancel()
+			}
+		}(request)
+	}
+}
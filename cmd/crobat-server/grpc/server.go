@@ -1,30 +1,132 @@
 package grpc
 
 import (
-	"fmt"
+	"context"
 	parser "github.com/Cgboal/DomainParser"
+	"github.com/cgboal/sonarsearch/pkg/grpcstatus"
 	"github.com/cgboal/sonarsearch/pkg/search"
 	crobat "github.com/cgboal/sonarsearch/proto"
-	"github.com/spf13/viper"
+	gogrpc "google.golang.org/grpc"
 	"io"
+	"strings"
 )
 
 var dp parser.Parser
 
+func init() {
+	dp = parser.NewDomainParser()
+}
+
 type CrobatServer struct{
 	crobat.UnimplementedCrobatServer
+	// Catalog holds the datasets which queries are run against
+	Catalog *search.Catalog
+	// Limits bounds the work done by each query
+	Limits search.QueryLimits
+	// Scheduler admits queries once there is room for them, and is shared
+	// with the REST API
+	Scheduler *search.Scheduler
+}
+
+// methodQueryTypes schedules each RPC alongside the REST endpoints which run
+// the same searches.
+var methodQueryTypes = map[string]search.QueryType{
+	"GetSubdomains":      search.SubdomainQuery,
+	"Resolve":            search.ResolveQuery,
+	"GetTLDs":            search.TLDQuery,
+	"GetSuffixDomains":   search.SuffixQuery,
+	"ReverseDNS":         search.ReverseQuery,
+	"ReverseDNSRange":    search.ReverseQuery,
+	"GetCNAMEChain":      search.RecordQuery,
+	"GetCNAMEAliases":    search.RecordQuery,
+	"GetNS":              search.RecordQuery,
+	"GetNSDomains":       search.RecordQuery,
+	"GetMX":              search.RecordQuery,
+	"GetMXDomains":       search.RecordQuery,
+	"CountSubdomains":    search.CountQuery,
+	"CountSuffixDomains": search.CountQuery,
+	"CountReverseDNS":    search.CountQuery,
+}
+
+func methodQueryType(fullMethod string) search.QueryType {
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	if queryType, exists := methodQueryTypes[method]; exists {
+		return queryType
+	}
+
+	return search.QueryType(method)
+}
+
+// UnaryInterceptor holds each unary RPC until the scheduler admits it.
+func (s *CrobatServer) UnaryInterceptor(ctx context.Context, req interface{}, info *gogrpc.UnaryServerInfo, handler gogrpc.UnaryHandler) (interface{}, error) {
+	release, err := s.Scheduler.Acquire(ctx, methodQueryType(info.FullMethod))
+	if err != nil {
+		return nil, grpcstatus.FromSearch(err)
+	}
+	defer release()
+
+	return handler(ctx, req)
+}
+
+// StreamInterceptor holds each streaming RPC until the scheduler admits it,
+// which keeps its slot until the stream ends.
+func (s *CrobatServer) StreamInterceptor(srv interface{}, stream gogrpc.ServerStream, info *gogrpc.StreamServerInfo, handler gogrpc.StreamHandler) error {
+	if info.FullMethod == batchMethod {
+		return handler(srv, stream)
+	}
+
+	release, err := s.Scheduler.Acquire(stream.Context(), methodQueryType(info.FullMethod))
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+	defer release()
+
+	return handler(srv, stream)
+}
+
+// queryCursor decodes the cursor which a query resumes from, if any.
+func queryCursor(query *crobat.QueryRequest) (*search.Cursor, error) {
+	if query.Cursor == "" {
+		return nil, nil
+	}
+
+	cursor, err := search.ParseCursor(query.Cursor)
+	if err != nil {
+		return nil, grpcstatus.FromSearch(err)
+	}
+
+	return cursor, nil
 }
 
 func (s *CrobatServer) GetSubdomains(query *crobat.QueryRequest, stream crobat.Crobat_GetSubdomainsServer) error {
-	searcher, err := search.NewDomainSearch(viper.GetString("domain_file"), query.Query, search.FullDomainNeedle)
+	ctx, cancel := s.Limits.Context(stream.Context())
+	defer cancel()
+
+	domains, err := s.Catalog.Dataset(search.DomainDataset)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+
+	// without the zone dataset, sub-zones are filtered from the domain dataset
+	zones, _ := s.Catalog.Dataset(search.ZoneDataset)
+
+	cursor, err := queryCursor(query)
 	if err != nil {
 		return err
 	}
+
+	searcher, err := search.NewSubzoneSearch(ctx, domains, zones, query.Query, cursor)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
 	defer searcher.Close()
 	for searcher.Next() {
 		domain := searcher.Text()
 		reply := &crobat.Domain{
 			Domain: domain,
+			Ip:     searcher.Value(),
+			Ips:    searcher.Values(),
+			Cursor: searcher.Cursor().String(),
 		}
 		if err := stream.Send(reply); err != nil {
 			return err
@@ -35,46 +137,120 @@ func (s *CrobatServer) GetSubdomains(query *crobat.QueryRequest, stream crobat.C
 		}
 	}
 
-	return nil
+	return grpcstatus.FromSearch(searcher.Error())
+}
+
+func (s *CrobatServer) Resolve(query *crobat.QueryRequest, stream crobat.Crobat_ResolveServer) error {
+	ctx, cancel := s.Limits.Context(stream.Context())
+	defer cancel()
+
+	dataset, err := s.Catalog.Dataset(search.DomainDataset)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+
+	ips, err := search.Resolve(ctx, dataset, query.Query)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+
+	return stream.Send(&crobat.Domain{
+		Domain: query.Query,
+		Ip:     ips[0],
+		Ips:    ips,
+	})
+}
+
+func (s *CrobatServer) GetSuffixDomains(query *crobat.QueryRequest, stream crobat.Crobat_GetSuffixDomainsServer) error {
+	ctx, cancel := s.Limits.Context(stream.Context())
+	defer cancel()
+
+	dataset, err := s.Catalog.Dataset(search.SuffixDataset)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+
+	cursor, err := queryCursor(query)
+	if err != nil {
+		return err
+	}
+
+	searcher, err := search.NewSuffixSearch(ctx, dataset, query.Query, cursor)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+	defer searcher.Close()
+	for searcher.Next() {
+		reply := &crobat.Domain{
+			Domain:         searcher.Text(),
+			SubdomainCount: int64(searcher.Subdomains()),
+			Cursor:         searcher.Cursor().String(),
+		}
+		if err := stream.Send(reply); err != nil {
+			return err
+		}
+	}
+
+	return grpcstatus.FromSearch(searcher.Error())
 }
 
 func (s *CrobatServer) GetTLDs(query *crobat.QueryRequest, stream crobat.Crobat_GetTLDsServer) error {
-	searcher, err := search.NewDomainSearch(viper.GetString("domain_file"), query.Query, search.DomainNeedle)
+	ctx, cancel := s.Limits.Context(stream.Context())
+	defer cancel()
+
+	dataset, err := s.Catalog.Dataset(search.DomainDataset)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+
+	cursor, err := queryCursor(query)
 	if err != nil {
 		return err
 	}
+
+	searcher, err := search.NewTLDSearch(ctx, dataset, query.Query, cursor)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
 	defer searcher.Close()
-	uniqueTLDs := map[string]struct{}{}
 	for searcher.Next() {
-		subdomain := searcher.Text()
-		domain := dp.ParseDomain(subdomain)
-		fullDomain := fmt.Sprintf("%s.%s", domain.Domain, domain.TLD)
-		_, exists := uniqueTLDs[fullDomain]
-		if !exists {
-			uniqueTLDs[fullDomain] = struct{}{}
-			reply := &crobat.Domain{
-				Domain: fullDomain,
-			}
-			if err := stream.Send(reply); err != nil {
-				return err
-			}
+		reply := &crobat.Domain{
+			Domain: searcher.Text(),
+			Cursor: searcher.Cursor().String(),
+		}
+		if err := stream.Send(reply); err != nil {
+			return err
 		}
 	}
-	return nil
+	return grpcstatus.FromSearch(searcher.Error())
 
 }
 
 func (s *CrobatServer) ReverseDNS(query *crobat.QueryRequest, stream crobat.Crobat_ReverseDNSServer) error {
-	searcher, err := search.NewReverseSearch(viper.GetString("reverse_file"), query.Query)
+	ctx, cancel := s.Limits.Context(stream.Context())
+	defer cancel()
+
+	dataset, err := s.Catalog.ReverseDataset(query.Query)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+
+	cursor, err := queryCursor(query)
 	if err != nil {
 		return err
 	}
+
+	searcher, err := search.NewReverseSearch(ctx, dataset, query.Query, cursor)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
 	defer searcher.Close()
 	for searcher.Next() {
 		result := searcher.Result()
 		reply := &crobat.Domain{
 			Domain: result.Domain,
-                        IPv4: result.IPv4,
+			Ip:     result.IP,
+			Cursor: searcher.Cursor().String(),
 		}
 		if err := stream.Send(reply); err != nil {
 			return err
@@ -84,20 +260,34 @@ func (s *CrobatServer) ReverseDNS(query *crobat.QueryRequest, stream crobat.Crob
 			break
 		}
 	}
-	return nil
+	return grpcstatus.FromSearch(searcher.Error())
 }
 
 func (s *CrobatServer) ReverseDNSRange(query *crobat.QueryRequest, stream crobat.Crobat_ReverseDNSRangeServer) error {
-	searcher, err := search.NewReverseSearch(viper.GetString("reverse_file"), query.Query)
+	ctx, cancel := s.Limits.Context(stream.Context())
+	defer cancel()
+
+	dataset, err := s.Catalog.ReverseDataset(query.Query)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+
+	cursor, err := queryCursor(query)
 	if err != nil {
 		return err
 	}
+
+	searcher, err := search.NewReverseSearch(ctx, dataset, query.Query, cursor)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
 	defer searcher.Close()
 	for searcher.Next() {
 		result := searcher.Result()
 		reply := &crobat.Domain{
 			Domain: result.Domain,
-                        IPv4: result.IPv4,
+			Ip:     result.IP,
+			Cursor: searcher.Cursor().String(),
 		}
 		if err := stream.Send(reply); err != nil {
 			return err
@@ -107,5 +297,237 @@ func (s *CrobatServer) ReverseDNSRange(query *crobat.QueryRequest, stream crobat
 			break
 		}
 	}
+	return grpcstatus.FromSearch(searcher.Error())
+}
+
+func (s *CrobatServer) GetCNAMEChain(query *crobat.QueryRequest, stream crobat.Crobat_GetCNAMEChainServer) error {
+	ctx, cancel := s.Limits.Context(stream.Context())
+	defer cancel()
+
+	dataset, err := s.Catalog.Dataset(search.CNAMEDataset)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+
+	chain, err := search.CNAMEChain(ctx, dataset, query.Query)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+
+	for _, record := range chain {
+		reply := &crobat.Domain{
+			Domain: record.Name,
+			Value:  record.Target,
+			Type:   "cname",
+		}
+		if err := stream.Send(reply); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+func (s *CrobatServer) GetCNAMEAliases(query *crobat.QueryRequest, stream crobat.Crobat_GetCNAMEAliasesServer) error {
+	ctx, cancel := s.Limits.Context(stream.Context())
+	defer cancel()
+
+	dataset, err := s.Catalog.Dataset(search.CNAMEReverseDataset)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+
+	cursor, err := queryCursor(query)
+	if err != nil {
+		return err
+	}
+
+	searcher, err := search.NewCNAMEAliasSearch(ctx, dataset, query.Query, cursor)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+	defer searcher.Close()
+	for searcher.Next() {
+		reply := &crobat.Domain{
+			Domain: searcher.Value(),
+			Value:  searcher.Text(),
+			Type:   "cname",
+			Cursor: searcher.Cursor().String(),
+		}
+		if err := stream.Send(reply); err != nil {
+			return err
+		}
+
+		if searcher.Error() == io.EOF {
+			break
+		}
+	}
+	return grpcstatus.FromSearch(searcher.Error())
+}
+
+// domainSender is implemented by the streams of every RPC which returns
+// Domain messages.
+type domainSender interface {
+	Send(*crobat.Domain) error
+}
+
+// sendRecords streams the results of a record search. Reverse searches hold
+// the record value in Text and the name in Value, so they are swapped back.
+func sendRecords(searcher *search.DomainSearch, stream domainSender, recordType string, reverse bool) error {
+	defer searcher.Close()
+	for searcher.Next() {
+		reply := &crobat.Domain{
+			Domain: searcher.Text(),
+			Value:  searcher.Value(),
+			Type:   recordType,
+			Cursor: searcher.Cursor().String(),
+		}
+		if reverse {
+			reply.Domain, reply.Value = reply.Value, reply.Domain
+		}
+		if err := stream.Send(reply); err != nil {
+			return err
+		}
+
+		if searcher.Error() == io.EOF {
+			break
+		}
+	}
+	return grpcstatus.FromSearch(searcher.Error())
+}
+
+func (s *CrobatServer) GetNS(query *crobat.QueryRequest, stream crobat.Crobat_GetNSServer) error {
+	ctx, cancel := s.Limits.Context(stream.Context())
+	defer cancel()
+
+	dataset, err := s.Catalog.Dataset(search.NSDataset)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+
+	cursor, err := queryCursor(query)
+	if err != nil {
+		return err
+	}
+
+	searcher, err := search.NewRecordValueSearch(ctx, dataset, query.Query, cursor)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+	return sendRecords(searcher, stream, "ns", false)
+}
+
+func (s *CrobatServer) GetNSDomains(query *crobat.QueryRequest, stream crobat.Crobat_GetNSDomainsServer) error {
+	ctx, cancel := s.Limits.Context(stream.Context())
+	defer cancel()
+
+	dataset, err := s.Catalog.Dataset(search.NSReverseDataset)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+
+	cursor, err := queryCursor(query)
+	if err != nil {
+		return err
+	}
+
+	searcher, err := search.NewRecordNameSearch(ctx, dataset, query.Query, cursor)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+	return sendRecords(searcher, stream, "ns", true)
+}
+
+func (s *CrobatServer) GetMX(query *crobat.QueryRequest, stream crobat.Crobat_GetMXServer) error {
+	ctx, cancel := s.Limits.Context(stream.Context())
+	defer cancel()
+
+	dataset, err := s.Catalog.Dataset(search.MXDataset)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+
+	cursor, err := queryCursor(query)
+	if err != nil {
+		return err
+	}
+
+	searcher, err := search.NewRecordValueSearch(ctx, dataset, query.Query, cursor)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+	return sendRecords(searcher, stream, "mx", false)
+}
+
+func (s *CrobatServer) GetMXDomains(query *crobat.QueryRequest, stream crobat.Crobat_GetMXDomainsServer) error {
+	ctx, cancel := s.Limits.Context(stream.Context())
+	defer cancel()
+
+	dataset, err := s.Catalog.Dataset(search.MXReverseDataset)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+
+	cursor, err := queryCursor(query)
+	if err != nil {
+		return err
+	}
+
+	searcher, err := search.NewRecordNameSearch(ctx, dataset, query.Query, cursor)
+	if err != nil {
+		return grpcstatus.FromSearch(err)
+	}
+	return sendRecords(searcher, stream, "mx", true)
+}
+
+func (s *CrobatServer) CountSubdomains(ctx context.Context, query *crobat.QueryRequest) (*crobat.Count, error) {
+	ctx, cancel := s.Limits.Context(ctx)
+	defer cancel()
+
+	domains, err := s.Catalog.Dataset(search.DomainDataset)
+	if err != nil {
+		return nil, grpcstatus.FromSearch(err)
+	}
+
+	zones, _ := s.Catalog.Dataset(search.ZoneDataset)
+
+	count, err := search.CountSubzone(ctx, domains, zones, query.Query)
+	if err != nil {
+		return nil, grpcstatus.FromSearch(err)
+	}
+
+	return &crobat.Count{Count: count}, nil
+}
+
+func (s *CrobatServer) CountSuffixDomains(ctx context.Context, query *crobat.QueryRequest) (*crobat.Count, error) {
+	ctx, cancel := s.Limits.Context(ctx)
+	defer cancel()
+
+	dataset, err := s.Catalog.Dataset(search.SuffixDataset)
+	if err != nil {
+		return nil, grpcstatus.FromSearch(err)
+	}
+
+	count, err := search.CountSuffixDomains(ctx, dataset, query.Query)
+	if err != nil {
+		return nil, grpcstatus.FromSearch(err)
+	}
+
+	return &crobat.Count{Count: count}, nil
+}
+
+func (s *CrobatServer) CountReverseDNS(ctx context.Context, query *crobat.QueryRequest) (*crobat.Count, error) {
+	ctx, cancel := s.Limits.Context(ctx)
+	defer cancel()
+
+	dataset, err := s.Catalog.ReverseDataset(query.Query)
+	if err != nil {
+		return nil, grpcstatus.FromSearch(err)
+	}
+
+	count, err := search.CountReverse(ctx, dataset, query.Query)
+	if err != nil {
+		return nil, grpcstatus.FromSearch(err)
+	}
+
+	return &crobat.Count{Count: count}, nil
+}
@@ -1,8 +1,19 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
 	"github.com/spf13/viper"
 	"github.com/cgboal/sonarsearch/cmd/crobat-server/rest"
+	"github.com/cgboal/sonarsearch/pkg/search"
 	"net"
 	crobat "github.com/cgboal/sonarsearch/proto"
 	"log"
@@ -13,11 +24,246 @@ import (
 func init() {
 	viper.SetEnvPrefix("crobat")
 	viper.AutomaticEnv()
+	viper.SetDefault("cache_backend", "redis")
+	viper.SetDefault("redis_addr", "localhost:6379")
+	viper.SetDefault("redis_mode", search.RedisStandalone)
+	viper.SetDefault("redis_pool_size", 64)
+	viper.SetDefault("redis_connect_timeout", "5s")
+	viper.SetDefault("query_concurrency", 32)
+	viper.SetDefault("query_queue_size", 256)
+}
+
+// setupCatalog opens the index of each dataset using the configured backend.
+func setupCatalog() *search.Catalog {
+	indexes := map[string]search.Index{}
+
+	switch backend := viper.GetString("cache_backend"); backend {
+	case "redis":
+		idx := openRedisIndex()
+		for _, dataset := range search.Datasets {
+			indexes[dataset] = idx
+		}
+	case "postgres":
+		idx, err := search.NewPostgresIndex(viper.GetString("postgres_url"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, dataset := range search.Datasets {
+			indexes[dataset] = idx
+		}
+	case "file":
+		// datasets other than domain and reverse are optional, as they
+		// depend on which record types were ingested
+		for _, dataset := range search.Datasets {
+			fileName := viper.GetString(dataset + "_index")
+			if fileName == "" && dataset != search.DomainDataset && dataset != search.ReverseDataset {
+				continue
+			}
+
+			idx, err := search.OpenFileIndex(fileName)
+			if err != nil {
+				log.Fatal(err)
+			}
+			indexes[dataset] = idx
+		}
+	case "bisect":
+		indexes = openBisectIndexes(32, 128)
+	default:
+		log.Fatalf("cache backend must be either 'redis', 'postgres', 'file' or 'bisect', got %s", backend)
+	}
+
+	if viper.GetBool("bisect_fallback") {
+		// the fallback must bucket IPs in the same way as the primary index
+		prefixLen, err := search.ReverseBucket(indexes[search.ReverseDataset])
+		if err != nil {
+			prefixLen = uint32(viper.GetUint("reverse_bucket"))
+		}
+
+		prefixLen6 := uint32(search.DefaultReverse6Bucket)
+		if idx, exists := indexes[search.Reverse6Dataset]; exists {
+			if bucket, err := search.Reverse6Bucket(idx); err == nil {
+				prefixLen6 = bucket
+			}
+		}
+
+		for dataset, fallback := range openBisectIndexes(prefixLen, prefixLen6) {
+			if idx, exists := indexes[dataset]; exists {
+				indexes[dataset] = &search.FallbackIndex{Primary: idx, Fallback: fallback}
+			}
+		}
+	}
+
+	// datasets other than domain and reverse are only served once their
+	// file is configured
+	catalog := search.NewCatalog()
+	for _, dataset := range search.Datasets {
+		fileName := viper.GetString(dataset + "_file")
+		if fileName == "" && dataset != search.DomainDataset && dataset != search.ReverseDataset {
+			continue
+		}
+
+		catalog.Add(search.NewFileDataset(dataset, fileName, indexes[dataset]))
+	}
+
+	return catalog
+}
+
+// openRedisIndex connects to the Redis deployment configured by the
+// CROBAT_REDIS_* settings, failing fast if it cannot be reached.
+func openRedisIndex() *search.RedisIndex {
+	tlsConfig, err := redisTLSConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	options := &redis.UniversalOptions{
+		Addrs:            strings.Split(viper.GetString("redis_addr"), ","),
+		MasterName:       viper.GetString("redis_master_name"),
+		Username:         viper.GetString("redis_username"),
+		Password:         viper.GetString("redis_password"),
+		SentinelPassword: viper.GetString("redis_sentinel_password"),
+		DB:               viper.GetInt("redis_db"),
+		PoolSize:         viper.GetInt("redis_pool_size"),
+		DialTimeout:      viper.GetDuration("redis_connect_timeout"),
+		TLSConfig:        tlsConfig,
+	}
+
+	client, err := search.NewRedisClient(viper.GetString("redis_mode"), options)
+	if err != nil {
+		log.Fatal(err)
+	}
+	idx := search.NewRedisIndex(client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), viper.GetDuration("redis_connect_timeout"))
+	defer cancel()
+	if err := idx.Ping(ctx); err != nil {
+		// the bisect fallback answers queries while redis is unreachable
+		if viper.GetBool("bisect_fallback") {
+			log.Printf("could not connect to redis at %s, falling back to bisect: %v", viper.GetString("redis_addr"), err)
+			return idx
+		}
+		log.Fatalf("could not connect to redis at %s: %v", viper.GetString("redis_addr"), err)
+	}
+
+	return idx
+}
+
+// redisTLSConfig returns the TLS config for connecting to Redis, or nil when
+// TLS is not enabled with CROBAT_REDIS_TLS.
+func redisTLSConfig() (*tls.Config, error) {
+	if !viper.GetBool("redis_tls") {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         viper.GetString("redis_tls_server_name"),
+		InsecureSkipVerify: viper.GetBool("redis_tls_insecure_skip_verify"),
+	}
+
+	if caFile := viper.GetString("redis_tls_ca_file"); caFile != "" {
+		ca, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+	}
+
+	// a client certificate is only needed when redis requires mutual TLS
+	if certFile := viper.GetString("redis_tls_cert_file"); certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, viper.GetString("redis_tls_key_file"))
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
 }
 
+func openBisectIndexes(prefixLen uint32, prefixLen6 uint32) map[string]search.Index {
+	indexes := map[string]search.Index{}
+	for _, dataset := range search.Datasets {
+		fileName := viper.GetString(dataset + "_file")
+		if fileName == "" && dataset != search.DomainDataset && dataset != search.ReverseDataset {
+			continue
+		}
+
+		datasetPrefixLen := prefixLen
+		if dataset == search.Reverse6Dataset {
+			datasetPrefixLen = prefixLen6
+		}
+
+		idx, err := search.NewDatasetBisectIndex(dataset, fileName, viper.GetInt("bisect_samples"), datasetPrefixLen)
+		if err != nil {
+			log.Fatal(err)
+		}
+		indexes[dataset] = idx
+	}
+
+	return indexes
+}
+
+// queryLimits reads the deadline and budget applied to each query, all of
+// which are unlimited by default.
+func queryLimits() search.QueryLimits {
+	return search.QueryLimits{
+		Timeout: viper.GetDuration("query_timeout"),
+		Budget: search.Budget{
+			MaxBytes:   viper.GetInt64("query_max_bytes"),
+			MaxRecords: viper.GetInt("query_max_records"),
+		},
+	}
+}
+
+// querySchedulerLimits reads how many queries may run and wait at once,
+// from CROBAT_QUERY_CONCURRENCY and CROBAT_QUERY_QUEUE_SIZE. Each query type
+// can be limited further, such as with CROBAT_QUERY_CONCURRENCY_ALL.
+func querySchedulerLimits() search.SchedulerLimits {
+	limits := search.SchedulerLimits{
+		Concurrency:     viper.GetInt("query_concurrency"),
+		MaxQueued:       viper.GetInt("query_queue_size"),
+		TypeConcurrency: map[search.QueryType]int{},
+	}
+	for _, queryType := range search.QueryTypes {
+		limits.TypeConcurrency[queryType] = viper.GetInt("query_concurrency_" + string(queryType))
+	}
+
+	return limits
+}
+
+// serveAdmin serves the scheduler's queues, along with the runtime's memory
+// stats, at /debug/vars on CROBAT_ADMIN_ADDR. They are kept off the public
+// listeners, and are not served unless an address is set.
+func serveAdmin() {
+	addr := viper.GetString("admin_addr")
+	if addr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	go func() {
+		log.Fatal(http.ListenAndServe(addr, mux))
+	}()
+}
 
 func main() {
-	restRouter := rest.NewRouter()
+	catalog := setupCatalog()
+	defer catalog.Close()
+	limits := queryLimits()
+
+	scheduler := search.NewScheduler(querySchedulerLimits())
+	expvar.Publish("scheduler", expvar.Func(func() interface{} {
+		return scheduler.Stats()
+	}))
+	serveAdmin()
+
+	crobatServer := cgrpc.CrobatServer{Catalog: catalog, Limits: limits, Scheduler: scheduler}
+	restRouter := rest.NewRouter(catalog, limits, scheduler, crobatServer.RunQuery)
 
 	go restRouter.Run(":1998")
 
@@ -25,9 +271,10 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	grpcServer := grpc.NewServer()
-	crobatServer := cgrpc.CrobatServer{}
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(crobatServer.UnaryInterceptor),
+		grpc.StreamInterceptor(crobatServer.StreamInterceptor),
+	)
 	crobat.RegisterCrobatServer(grpcServer, &crobatServer)
 	grpcServer.Serve(lis)
 }
-
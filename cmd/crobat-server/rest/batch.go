@@ -0,0 +1,141 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/cgboal/sonarsearch/pkg/search"
+	crobat "github.com/cgboal/sonarsearch/proto"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BatchFunc runs a single query of a batch, passing each result to send, and
+// fails with a gRPC status. Batches are answered by the gRPC server, so that
+// both APIs accept the same query types.
+type BatchFunc func(ctx context.Context, queryType string, query *crobat.QueryRequest, send func(*crobat.Domain) error) error
+
+var runQuery BatchFunc
+
+// maxBatchQueries bounds how many queries a single batch may hold.
+const maxBatchQueries = 10000
+
+// statusCodes names the statuses of failed batch queries with the error
+// codes used by the rest of the REST API.
+var statusCodes = map[codes.Code]string{
+	codes.NotFound:           "not_found",
+	codes.InvalidArgument:    "invalid_query",
+	codes.Unavailable:        "unavailable",
+	codes.DeadlineExceeded:   "timeout",
+	codes.ResourceExhausted:  "resource_exhausted",
+	codes.FailedPrecondition: "stale_cursor",
+	codes.Canceled:           "canceled",
+}
+
+type batchQuery struct {
+	// ID tags the results of the query, and defaults to the query itself
+	ID string `json:"id"`
+	// Type names the endpoint which would answer the query, and defaults to
+	// subdomains
+	Type   string `json:"type"`
+	Query  string `json:"query"`
+	Cursor string `json:"cursor"`
+}
+
+// batchLine is a line of the response to a batch, holding either a result,
+// or the end of a query along with the error which stopped it.
+type batchLine struct {
+	ID     string         `json:"id"`
+	Query  string         `json:"query"`
+	Result *crobat.Domain `json:"result,omitempty"`
+	Error  string         `json:"error,omitempty"`
+	Code   string         `json:"code,omitempty"`
+	Done   bool           `json:"done,omitempty"`
+}
+
+// Batch runs the queries posted as {"queries": [...]}, and streams their
+// results back as NDJSON as they are found. Queries which fail report their
+// error on their last line, without stopping the rest of the batch.
+func Batch(c *gin.Context) {
+	var batch struct {
+		Queries []batchQuery `json:"queries"`
+	}
+	if err := c.ShouldBindJSON(&batch); err != nil {
+		searchError(c, &search.QueryError{Reason: "malformed batch: " + err.Error()})
+		return
+	}
+	if len(batch.Queries) == 0 || len(batch.Queries) > maxBatchQueries {
+		searchError(c, &search.QueryError{Reason: fmt.Sprintf("a batch must hold between 1 and %d queries", maxBatchQueries)})
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	var writeLock sync.Mutex
+	write := func(line batchLine) error {
+		writeLock.Lock()
+		defer writeLock.Unlock()
+
+		encoded, err := json.Marshal(line)
+		if err != nil {
+			return err
+		}
+		if _, err := c.Writer.Write(append(encoded, '\n')); err != nil {
+			return err
+		}
+		// the results of a query are flushed together once it is done
+		if line.Done {
+			c.Writer.Flush()
+		}
+		return nil
+	}
+
+	// once a write fails the client has gone, so the rest of the batch is
+	// canceled rather than run for nobody
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	running := make(chan struct{}, search.BatchConcurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for _, query := range batch.Queries {
+		select {
+		case running <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		if query.ID == "" {
+			query.ID = query.Query
+		}
+
+		wg.Add(1)
+		go func(query batchQuery) {
+			defer wg.Done()
+			defer func() { <-running }()
+
+			request := &crobat.QueryRequest{Query: query.Query, Cursor: query.Cursor}
+			err := runQuery(ctx, query.Type, request, func(domain *crobat.Domain) error {
+				return write(batchLine{ID: query.ID, Query: query.Query, Result: domain})
+			})
+
+			done := batchLine{ID: query.ID, Query: query.Query, Done: true}
+			if err != nil {
+				st := status.Convert(err)
+				done.Error = st.Message()
+				done.Code = statusCodes[st.Code()]
+				if done.Code == "" {
+					done.Code = "internal"
+				}
+			}
+			if err := write(done); err != nil {
+				cancel()
+			}
+		}(query)
+	}
+}
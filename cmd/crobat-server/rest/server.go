@@ -1,20 +1,85 @@
 package rest
 
 import (
+	"context"
+	"errors"
+	"io"
+	"net"
 	"net/http"
 
 	"fmt"
 	"github.com/Cgboal/DomainParser"
 	"github.com/cgboal/sonarsearch/pkg/search"
 	"github.com/gin-gonic/gin"
-	"github.com/spf13/viper"
 	"strconv"
 )
 
 var dp parser.Parser
 
-var reverseQueries chan search.ReverseQuery
-var domainQueries chan search.DomainQuery
+var catalog *search.Catalog
+var queryLimits search.QueryLimits
+
+var scheduler *search.Scheduler
+
+// queryContext derives the context of a query from its request, so that the
+// search stops if the client goes away.
+func queryContext(c *gin.Context) (context.Context, context.CancelFunc) {
+	return queryLimits.Context(c.Request.Context())
+}
+
+// searchError responds with the error which stopped a search, along with a
+// code which callers can tell errors apart by.
+func searchError(c *gin.Context, err error) {
+	status, code := errorStatus(err)
+	if errors.Is(err, search.ErrQueueFull) {
+		c.Header("Retry-After", "1")
+	}
+
+	c.JSON(status, gin.H{"error": err.Error(), "code": code})
+}
+
+// errorStatus returns the HTTP status and error code of err.
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, search.ErrNotFound):
+		return http.StatusNotFound, "not_found"
+	case errors.Is(err, search.ErrInvalidQuery):
+		return http.StatusBadRequest, "invalid_query"
+	case errors.Is(err, search.ErrQueueFull):
+		return http.StatusTooManyRequests, "queue_full"
+	case errors.Is(err, search.ErrUnavailable):
+		return http.StatusServiceUnavailable, "unavailable"
+	case errors.Is(err, search.ErrDeadlineExceeded):
+		return http.StatusGatewayTimeout, "timeout"
+	case errors.Is(err, search.ErrBudgetExceeded):
+		return http.StatusUnprocessableEntity, "budget_exceeded"
+	case errors.Is(err, search.ErrInvalidCursor):
+		return http.StatusBadRequest, "invalid_cursor"
+	case errors.Is(err, search.ErrStaleCursor):
+		return http.StatusGone, "stale_cursor"
+	case errors.Is(err, search.ErrCanceled):
+		// the client has gone away, so is not waiting for the response
+		return 499, "canceled"
+	default:
+		return http.StatusInternalServerError, "internal"
+	}
+}
+
+// schedule holds a request until the scheduler admits a query of queryType,
+// which keeps its slot until the response has been written.
+func schedule(queryType search.QueryType) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		release, err := scheduler.Acquire(c.Request.Context(), queryType)
+		if err != nil {
+			searchError(c, err)
+			c.Abort()
+			return
+		}
+		defer release()
+
+		c.Next()
+	}
+}
 
 func paginationHelper(c *gin.Context) (int, int) {
 	limitString := c.Query("limit")
@@ -38,122 +103,655 @@ func paginationHelper(c *gin.Context) (int, int) {
 
 }
 
+// cursorHelper decodes the cursor which a request resumes from, if any. Pages
+// are then read from the cursor, rather than by skipping earlier results.
+func cursorHelper(c *gin.Context) (*search.Cursor, error) {
+	token := c.Query("cursor")
+	if token == "" {
+		return nil, nil
+	}
+
+	return search.ParseCursor(token)
+}
+
+// nextLink links to the next page of results, which resumes from next.
+func nextLink(c *gin.Context, next *search.Cursor) {
+	if next == nil {
+		return
+	}
+
+	nextURL := *c.Request.URL
+	query := nextURL.Query()
+	query.Del("page")
+	query.Set("cursor", next.String())
+	nextURL.RawQuery = query.Encode()
+	c.Header("Link", fmt.Sprintf("<%s>; rel=\"next\"", nextURL.String()))
+}
+
+// listEnvelope wraps a page of results when ?envelope=true is passed, along
+// with the total number of results and where the page lies within them.
+type listEnvelope struct {
+	Results interface{} `json:"results"`
+	Total   int64       `json:"total"`
+	// Page is left out when paging with cursors
+	Page  int    `json:"page,omitempty"`
+	Limit int    `json:"limit"`
+	Next  string `json:"next,omitempty"`
+}
+
+// listResponse responds with a page of results. The total is only counted
+// when the envelope is asked for, as it may mean scanning every result.
+func listResponse(c *gin.Context, results interface{}, next *search.Cursor, total func() (int64, error)) {
+	nextLink(c, next)
+	if envelope, _ := strconv.ParseBool(c.Query("envelope")); !envelope {
+		c.JSON(http.StatusOK, results)
+		return
+	}
+
+	count, err := total()
+	if err != nil {
+		searchError(c, err)
+		return
+	}
+
+	skip, limit := paginationHelper(c)
+	response := listEnvelope{
+		Results: results,
+		Total:   count,
+		Limit:   limit,
+	}
+	if c.Query("cursor") == "" && limit > 0 {
+		response.Page = skip/limit + 1
+	}
+	if next != nil {
+		response.Next = next.String()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// countFunc counts the results of a query, for the count endpoints and the
+// totals of list responses.
+type countFunc func(ctx context.Context, query string) (int64, error)
+
+func countSubdomains(ctx context.Context, query string) (int64, error) {
+	domains, err := catalog.Dataset(search.DomainDataset)
+	if err != nil {
+		return 0, err
+	}
+
+	zones, _ := catalog.Dataset(search.ZoneDataset)
+	return search.CountSubzone(ctx, domains, zones, query)
+}
+
+func countTLDs(ctx context.Context, query string) (int64, error) {
+	dataset, err := catalog.Dataset(search.DomainDataset)
+	if err != nil {
+		return 0, err
+	}
+
+	return search.CountTLDs(ctx, dataset, query)
+}
+
+func countSuffixDomains(ctx context.Context, suffix string) (int64, error) {
+	dataset, err := catalog.Dataset(search.SuffixDataset)
+	if err != nil {
+		return 0, err
+	}
+
+	return search.CountSuffixDomains(ctx, dataset, suffix)
+}
+
+func countReverse(ctx context.Context, query string) (int64, error) {
+	dataset, err := catalog.ReverseDataset(query)
+	if err != nil {
+		return 0, err
+	}
+
+	return search.CountReverse(ctx, dataset, query)
+}
+
+// countRecords counts the results of searching the named dataset with
+// needleFunc.
+func countRecords(name string, needleFunc func(parser.Domain) (string, error)) countFunc {
+	return func(ctx context.Context, query string) (int64, error) {
+		dataset, err := catalog.Dataset(name)
+		if err != nil {
+			return 0, err
+		}
+
+		return search.CountRecords(ctx, dataset, query, needleFunc)
+	}
+}
+
+// CountResults responds with the number of results which the list endpoint
+// for the query in param would return. Reverse lookups of a range also take
+// the mask from the cidr param.
+func CountResults(counter countFunc, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := queryContext(c)
+		defer cancel()
+
+		query := c.Param(param)
+		if cidr := c.Param("cidr"); cidr != "" {
+			query = fmt.Sprintf("%s/%s", query, cidr)
+		}
+
+		count, err := counter(ctx, query)
+		if err != nil {
+			searchError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"count": count})
+	}
+}
+
 func FindSubdomains(c *gin.Context) {
+	ctx, cancel := queryContext(c)
+	defer cancel()
+
 	query := c.Param("domain")
 	skip, take := paginationHelper(c)
-	responseChan := make(chan search.DomainResponse, 1)
+	cursor, err := cursorHelper(c)
+	if err != nil {
+		searchError(c, err)
+		return
+	}
 
-	defer close(responseChan)
+	domains, err := catalog.Dataset(search.DomainDataset)
+	if err != nil {
+		searchError(c, err)
+		return
+	}
 
-	domainQueries <- search.DomainQuery{Query: query, Take: take, Skip: skip, ResponseChannel: responseChan, NeedleFunc: search.FullDomainNeedle}
+	// without the zone dataset, sub-zones are filtered from the domain dataset
+	zones, _ := catalog.Dataset(search.ZoneDataset)
 
-	response := <- responseChan
+	searcher, err := search.NewSubzoneSearch(ctx, domains, zones, query, cursor)
+	if err != nil {
+		searchError(c, err)
+		return
+	}
+	defer searcher.Close()
+	if cursor == nil {
+		searcher.Skip(skip)
+	}
+	if stream(c, searcher, []string{"domain", "ips"}, func() []interface{} {
+		ips := searcher.Values()
+		if ips == nil {
+			ips = []string{}
+		}
+		return []interface{}{searcher.Text(), ips}
+	}) {
+		return
+	}
+	subdomains, values := searcher.TakeValues(take)
+	next := search.NextCursor(searcher)
+	if err := searcher.Error(); err != nil && err != io.EOF {
+		searchError(c, err)
+		return
+	}
+
+	total := func() (int64, error) {
+		return countSubdomains(ctx, query)
+	}
 
-	if response.Err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": response.Err.Error()})
+	if withIPs, _ := strconv.ParseBool(c.Query("ips")); withIPs {
+		results := []gin.H{}
+		for _, subdomain := range subdomains {
+			ips := values[subdomain]
+			if ips == nil {
+				ips = []string{}
+			}
+			results = append(results, gin.H{"domain": subdomain, "ips": ips})
+		}
+		listResponse(c, results, next, total)
 		return
 	}
-	c.JSON(http.StatusOK, response.Subdomains)
+
+	listResponse(c, subdomains, next, total)
 }
 
-func FindAll(c *gin.Context) {
-	searcher, err := search.NewDomainSearch(viper.GetString("domain_file"), c.Param("domain"), search.DomainNeedle)
+func FindSuffixDomains(c *gin.Context) {
+	ctx, cancel := queryContext(c)
+	defer cancel()
+
+	dataset, err := catalog.Dataset(search.SuffixDataset)
+	if err != nil {
+		searchError(c, err)
+		return
+	}
+
+	cursor, err := cursorHelper(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		searchError(c, err)
+		return
+	}
+
+	searcher, err := search.NewSuffixSearch(ctx, dataset, c.Param("suffix"), cursor)
+	if err != nil {
+		searchError(c, err)
 		return
 	}
 	defer searcher.Close()
 	skip, limit := paginationHelper(c)
-	subdomains := searcher.Skip(skip).Take(limit)
-	c.JSON(http.StatusOK, subdomains)
+	if cursor == nil {
+		searcher.Skip(skip)
+	}
+	if stream(c, searcher, []string{"domain", "subdomains"}, func() []interface{} {
+		return []interface{}{searcher.Text(), searcher.Subdomains()}
+	}) {
+		return
+	}
+
+	withCounts, _ := strconv.ParseBool(c.Query("counts"))
+	domains := []string{}
+	counts := []gin.H{}
+	for i := 0; i < limit && searcher.Next(); i++ {
+		if withCounts {
+			counts = append(counts, gin.H{"domain": searcher.Text(), "subdomains": searcher.Subdomains()})
+		} else {
+			domains = append(domains, searcher.Text())
+		}
+	}
+	next := search.NextCursor(searcher)
+	if err := searcher.Error(); err != nil && err != io.EOF {
+		searchError(c, err)
+		return
+	}
+
+	total := func() (int64, error) {
+		return countSuffixDomains(ctx, c.Param("suffix"))
+	}
+
+	if withCounts {
+		listResponse(c, counts, next, total)
+		return
+	}
+	listResponse(c, domains, next, total)
 }
 
-func FindTLDs(c *gin.Context) {
-	searcher, err := search.NewDomainSearch(viper.GetString("domain_file"), c.Param("domain"), search.DomainNeedle)
+func ResolveDomain(c *gin.Context) {
+	ctx, cancel := queryContext(c)
+	defer cancel()
+
+	dataset, err := catalog.Dataset(search.DomainDataset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		searchError(c, err)
+		return
+	}
+
+	ips, err := search.Resolve(ctx, dataset, c.Param("domain"))
+	if err != nil {
+		searchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ips)
+}
+
+func FindAll(c *gin.Context) {
+	ctx, cancel := queryContext(c)
+	defer cancel()
+
+	dataset, err := catalog.Dataset(search.DomainDataset)
+	if err != nil {
+		searchError(c, err)
+		return
+	}
+
+	cursor, err := cursorHelper(c)
+	if err != nil {
+		searchError(c, err)
+		return
+	}
+
+	searcher, err := search.NewDomainSearch(ctx, dataset, c.Param("domain"), search.DomainNeedle, cursor)
+	if err != nil {
+		searchError(c, err)
 		return
 	}
 	defer searcher.Close()
 	skip, limit := paginationHelper(c)
-	subdomains := searcher.Skip(skip).Take(limit)
-	uniqueTLDs := map[string]struct{}{}
-	for _, subdomain := range subdomains {
-		domain := dp.ParseDomain(subdomain)
-		fullDomain := fmt.Sprintf("%s.%s", domain.Domain, domain.TLD)
-		_, exists := uniqueTLDs[fullDomain]
-		if !exists {
-			uniqueTLDs[fullDomain] = struct{}{}
-		}
+	if cursor == nil {
+		searcher.Skip(skip)
+	}
+	if stream(c, searcher, []string{"domain"}, func() []interface{} {
+		return []interface{}{searcher.Text()}
+	}) {
+		return
 	}
+	subdomains := searcher.Take(limit)
+	next := search.NextCursor(searcher)
+	if err := searcher.Error(); err != nil && err != io.EOF {
+		searchError(c, err)
+		return
+	}
+	listResponse(c, subdomains, next, func() (int64, error) {
+		return countRecords(search.DomainDataset, search.DomainNeedle)(ctx, c.Param("domain"))
+	})
+}
 
-	results := []string{}
-	for domain := range uniqueTLDs {
-		results = append(results, domain)
+func FindTLDs(c *gin.Context) {
+	ctx, cancel := queryContext(c)
+	defer cancel()
+
+	dataset, err := catalog.Dataset(search.DomainDataset)
+	if err != nil {
+		searchError(c, err)
+		return
 	}
 
-	c.JSON(http.StatusOK, results)
+	cursor, err := cursorHelper(c)
+	if err != nil {
+		searchError(c, err)
+		return
+	}
 
+	searcher, err := search.NewTLDSearch(ctx, dataset, c.Param("domain"), cursor)
+	if err != nil {
+		searchError(c, err)
+		return
+	}
+	defer searcher.Close()
+	skip, limit := paginationHelper(c)
+	if cursor == nil {
+		search.Skip(searcher, skip)
+	}
+	if stream(c, searcher, []string{"domain"}, func() []interface{} {
+		return []interface{}{searcher.Text()}
+	}) {
+		return
+	}
+	results := searcher.Take(limit)
+	next := search.NextCursor(searcher)
+	if err := searcher.Error(); err != nil && err != io.EOF {
+		searchError(c, err)
+		return
+	}
+	listResponse(c, results, next, func() (int64, error) {
+		return countTLDs(ctx, c.Param("domain"))
+	})
 }
 
 func ReverseDNS(c *gin.Context) {
 	query := c.Param("ip")
+	// results are keyed by the canonical form of the address, such as
+	// 2001:db8::1 for 2001:0db8::1
+	key := query
+	if ip := net.ParseIP(query); ip != nil {
+		key = ip.String()
+	}
+	reverseLookup(c, query, func(results map[string][]string) interface{} {
+		return results[key]
+	})
+}
+
+func ReverseDNSCIDR(c *gin.Context) {
+	query := fmt.Sprintf("%s/%s", c.Param("ip"), c.Param("cidr"))
+	reverseLookup(c, query, func(results map[string][]string) interface{} {
+		return results
+	})
+}
+
+// reverseLookup responds with a page of the names found for an address or
+// range, shaped by resultsFunc.
+func reverseLookup(c *gin.Context, query string, resultsFunc func(map[string][]string) interface{}) {
+	ctx, cancel := queryContext(c)
+	defer cancel()
+
 	skip, take := paginationHelper(c)
-	responseChan := make(chan search.ReverseResponse, 1)
+	cursor, err := cursorHelper(c)
+	if err != nil {
+		searchError(c, err)
+		return
+	}
 
-	defer close(responseChan)
+	dataset, err := catalog.ReverseDataset(query)
+	if err != nil {
+		searchError(c, err)
+		return
+	}
 
-	reverseQueries <- search.ReverseQuery{Query: query, Take: take, Skip: skip, ResponseChannel: responseChan}
+	searcher, err := search.NewReverseSearch(ctx, dataset, query, cursor)
+	if err != nil {
+		searchError(c, err)
+		return
+	}
+	defer searcher.Close()
+	if cursor == nil {
+		search.Skip(searcher, skip)
+	}
+	if stream(c, searcher, []string{"ip", "domain"}, func() []interface{} {
+		return []interface{}{searcher.Result().IP, searcher.Result().Domain}
+	}) {
+		return
+	}
+	results := searcher.Take(take)
+	next := search.NextCursor(searcher)
+	if err := searcher.Error(); err != nil && err != io.EOF {
+		searchError(c, err)
+		return
+	}
+
+	listResponse(c, resultsFunc(results), next, func() (int64, error) {
+		return countReverse(ctx, query)
+	})
+}
 
-	response := <-responseChan
+func CNAMEChain(c *gin.Context) {
+	ctx, cancel := queryContext(c)
+	defer cancel()
 
-	if response.Err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": response.Err.Error()})
+	dataset, err := catalog.Dataset(search.CNAMEDataset)
+	if err != nil {
+		searchError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.Results[query])
+	chain, err := search.CNAMEChain(ctx, dataset, c.Param("domain"))
+	if err != nil {
+		searchError(c, err)
+		return
+	}
+
+	targets := []string{}
+	for _, record := range chain {
+		targets = append(targets, record.Target)
+	}
+
+	c.JSON(http.StatusOK, targets)
 }
 
-func ReverseDNSCIDR(c *gin.Context) {
-	query := fmt.Sprintf("%s/%s", c.Param("ip"), c.Param("cidr"))
-	skip, take := paginationHelper(c)
-	responseChan := make(chan search.ReverseResponse, 1)
+func CNAMEAliases(c *gin.Context) {
+	ctx, cancel := queryContext(c)
+	defer cancel()
 
-	defer close(responseChan)
+	dataset, err := catalog.Dataset(search.CNAMEReverseDataset)
+	if err != nil {
+		searchError(c, err)
+		return
+	}
 
-	reverseQueries <- search.ReverseQuery{Query: query, Take: take, Skip: skip, ResponseChannel: responseChan}
+	cursor, err := cursorHelper(c)
+	if err != nil {
+		searchError(c, err)
+		return
+	}
 
-	response := <-responseChan
+	searcher, err := search.NewCNAMEAliasSearch(ctx, dataset, c.Param("domain"), cursor)
+	if err != nil {
+		searchError(c, err)
+		return
+	}
+	defer searcher.Close()
+	skip, limit := paginationHelper(c)
+	if cursor == nil {
+		searcher.Skip(skip)
+	}
+	if stream(c, searcher, []string{"target", "domain"}, func() []interface{} {
+		return []interface{}{searcher.Text(), searcher.Value()}
+	}) {
+		return
+	}
 
-	if response.Err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": response.Err.Error()})
+	aliases := map[string][]string{}
+	for i := 0; i < limit && searcher.Next(); i++ {
+		aliases[searcher.Text()] = append(aliases[searcher.Text()], searcher.Value())
+	}
+	next := search.NextCursor(searcher)
+	if err := searcher.Error(); err != nil && err != io.EOF {
+		searchError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.Results)
-	return
+	listResponse(c, aliases, next, func() (int64, error) {
+		return countRecords(search.CNAMEReverseDataset, search.TargetNeedle)(ctx, c.Param("domain"))
+	})
+}
+
+// RecordValues returns the values of the records of a given type held by a
+// name, such as its nameservers.
+func RecordValues(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := queryContext(c)
+		defer cancel()
+
+		dataset, err := catalog.Dataset(name)
+		if err != nil {
+			searchError(c, err)
+			return
+		}
+
+		cursor, err := cursorHelper(c)
+		if err != nil {
+			searchError(c, err)
+			return
+		}
+
+		searcher, err := search.NewRecordValueSearch(ctx, dataset, c.Param("domain"), cursor)
+		if err != nil {
+			searchError(c, err)
+			return
+		}
+		defer searcher.Close()
+		skip, limit := paginationHelper(c)
+		if cursor == nil {
+			searcher.Skip(skip)
+		}
+		if stream(c, searcher, []string{"domain", "value"}, func() []interface{} {
+			return []interface{}{searcher.Text(), searcher.Value()}
+		}) {
+			return
+		}
+
+		values := []string{}
+		for i := 0; i < limit && searcher.Next(); i++ {
+			values = append(values, searcher.Value())
+		}
+		next := search.NextCursor(searcher)
+		if err := searcher.Error(); err != nil && err != io.EOF {
+			searchError(c, err)
+			return
+		}
+
+		listResponse(c, values, next, func() (int64, error) {
+			return countRecords(name, search.ExactDomainNeedle)(ctx, c.Param("domain"))
+		})
+	}
+}
+
+// RecordNames returns the names holding records of a given type which point
+// at a host, grouped by the host, using the reverse dataset.
+func RecordNames(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := queryContext(c)
+		defer cancel()
+
+		dataset, err := catalog.Dataset(name)
+		if err != nil {
+			searchError(c, err)
+			return
+		}
+
+		cursor, err := cursorHelper(c)
+		if err != nil {
+			searchError(c, err)
+			return
+		}
+
+		searcher, err := search.NewRecordNameSearch(ctx, dataset, c.Param("domain"), cursor)
+		if err != nil {
+			searchError(c, err)
+			return
+		}
+		defer searcher.Close()
+		skip, limit := paginationHelper(c)
+		if cursor == nil {
+			searcher.Skip(skip)
+		}
+		if stream(c, searcher, []string{"host", "domain"}, func() []interface{} {
+			return []interface{}{searcher.Text(), searcher.Value()}
+		}) {
+			return
+		}
+
+		names := map[string][]string{}
+		for i := 0; i < limit && searcher.Next(); i++ {
+			names[searcher.Text()] = append(names[searcher.Text()], searcher.Value())
+		}
+		next := search.NextCursor(searcher)
+		if err := searcher.Error(); err != nil && err != io.EOF {
+			searchError(c, err)
+			return
+		}
+
+		listResponse(c, names, next, func() (int64, error) {
+			return countRecords(name, search.TargetNeedle)(ctx, c.Param("domain"))
+		})
+	}
 }
 
-func NewRouter() *gin.Engine {
+func NewRouter(datasets *search.Catalog, limits search.QueryLimits, queries *search.Scheduler, batch BatchFunc) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 
+	catalog = datasets
+	queryLimits = limits
+	scheduler = queries
+	runQuery = batch
+
 	r := gin.New()
 	r.Use(gin.Recovery())
 
-	reverseQueries = make(chan search.ReverseQuery, 1)
-	domainQueries = make(chan search.DomainQuery, 1)
-
-	search.NewReversePool(reverseQueries)
-	search.NewDomainPool(domainQueries)
-
 	dp = parser.NewDomainParser()
 
-	r.GET("/subdomains/:domain", FindSubdomains)
-	r.GET("/tlds/:domain", FindTLDs)
-	r.GET("/all/:domain", FindAll)
-	r.GET("/reverse/:ip", ReverseDNS)
-	r.GET("/reverse/:ip/:cidr", ReverseDNSCIDR)
+	r.GET("/subdomains/:domain", schedule(search.SubdomainQuery), FindSubdomains)
+	r.GET("/resolve/:domain", schedule(search.ResolveQuery), ResolveDomain)
+	r.GET("/tlds/:domain", schedule(search.TLDQuery), FindTLDs)
+	r.GET("/suffix/:suffix", schedule(search.SuffixQuery), FindSuffixDomains)
+	r.GET("/all/:domain", schedule(search.AllQuery), FindAll)
+	r.GET("/reverse/:ip", schedule(search.ReverseQuery), ReverseDNS)
+	r.GET("/reverse/:ip/:cidr", schedule(search.ReverseQuery), ReverseDNSCIDR)
+	r.GET("/cname/:domain", schedule(search.RecordQuery), CNAMEChain)
+	r.GET("/aliases/:domain", schedule(search.RecordQuery), CNAMEAliases)
+	r.GET("/ns/:domain", schedule(search.RecordQuery), RecordValues(search.NSDataset))
+	r.GET("/ns/:domain/domains", schedule(search.RecordQuery), RecordNames(search.NSReverseDataset))
+	r.GET("/mx/:domain", schedule(search.RecordQuery), RecordValues(search.MXDataset))
+	r.GET("/mx/:domain/domains", schedule(search.RecordQuery), RecordNames(search.MXReverseDataset))
+
+	// each query of a batch is scheduled on its own
+	r.POST("/batch", Batch)
+
+	r.GET("/count/subdomains/:domain", schedule(search.CountQuery), CountResults(countSubdomains, "domain"))
+	r.GET("/count/all/:domain", schedule(search.CountQuery), CountResults(countRecords(search.DomainDataset, search.DomainNeedle), "domain"))
+	r.GET("/count/tlds/:domain", schedule(search.CountQuery), CountResults(countTLDs, "domain"))
+	r.GET("/count/suffix/:suffix", schedule(search.CountQuery), CountResults(countSuffixDomains, "suffix"))
+	r.GET("/count/reverse/:ip", schedule(search.CountQuery), CountResults(countReverse, "ip"))
+	r.GET("/count/reverse/:ip/:cidr", schedule(search.CountQuery), CountResults(countReverse, "ip"))
+	r.GET("/count/aliases/:domain", schedule(search.CountQuery), CountResults(countRecords(search.CNAMEReverseDataset, search.TargetNeedle), "domain"))
+	r.GET("/count/ns/:domain/domains", schedule(search.CountQuery), CountResults(countRecords(search.NSReverseDataset, search.TargetNeedle), "domain"))
+	r.GET("/count/mx/:domain/domains", schedule(search.CountQuery), CountResults(countRecords(search.MXReverseDataset, search.TargetNeedle), "domain"))
 
 	return r
 }
@@ -0,0 +1,165 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cgboal/sonarsearch/pkg/search"
+	"github.com/gin-gonic/gin"
+)
+
+// testRouter serves datasets holding the given lines, keyed by dataset name.
+func testRouter(t *testing.T, datasets map[string][]string) *gin.Engine {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, lines := range datasets {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	catalog, err := search.OpenDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { catalog.Close() })
+
+	return NewRouter(catalog, search.QueryLimits{}, nil, nil)
+}
+
+func get(t *testing.T, router *gin.Engine, path string, response interface{}) int {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	if err := json.Unmarshal(recorder.Body.Bytes(), response); err != nil {
+		t.Fatalf("GET %s: %v: %s", path, err, recorder.Body)
+	}
+
+	return recorder.Code
+}
+
+func TestReverseDNS(t *testing.T) {
+	router := testRouter(t, map[string][]string{
+		search.ReverseDataset: {"16909060,www.example.com"},
+		search.Reverse6Dataset: {
+			"20010db8000000000000000000000001,a.example.com",
+			"20010db8000000000000000000000001,b.example.com",
+		},
+	})
+
+	tests := []struct {
+		path    string
+		domains []string
+	}{
+		{path: "/reverse/1.2.3.4", domains: []string{"www.example.com"}},
+		{path: "/reverse/2001:db8::1", domains: []string{"a.example.com", "b.example.com"}},
+		// addresses are found whichever form they are written in
+		{path: "/reverse/2001:0db8::1", domains: []string{"a.example.com", "b.example.com"}},
+		{path: "/reverse/2001:db8:0:0:0:0:0:1", domains: []string{"a.example.com", "b.example.com"}},
+	}
+
+	for _, test := range tests {
+		var domains []string
+		if code := get(t, router, test.path, &domains); code != http.StatusOK {
+			t.Errorf("GET %s = %d", test.path, code)
+		}
+		if !reflect.DeepEqual(domains, test.domains) {
+			t.Errorf("GET %s = %v, want %v", test.path, domains, test.domains)
+		}
+	}
+}
+
+func TestFindTLDs(t *testing.T) {
+	router := testRouter(t, map[string][]string{
+		search.DomainDataset: {
+			"example,co.uk,mail,1.2.3.4",
+			"example,co.uk,www,1.2.3.5",
+			"example,com,a,1.2.3.6",
+			"example,com,b,1.2.3.7",
+			"example,org,www,1.2.3.8",
+		},
+	})
+	want := []string{"example.co.uk", "example.com", "example.org"}
+
+	var domains []string
+	if code := get(t, router, "/tlds/example.com", &domains); code != http.StatusOK {
+		t.Errorf("GET /tlds/example.com = %d", code)
+	}
+	if !reflect.DeepEqual(domains, want) {
+		t.Errorf("GET /tlds/example.com = %v, want %v", domains, want)
+	}
+
+	// limits count domains rather than lines, and following the next links
+	// returns each domain once
+	var paged []string
+	path := "/tlds/example.com?limit=1"
+	for page := 0; path != "" && page < len(want)+1; page++ {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		var domains []string
+		if err := json.Unmarshal(recorder.Body.Bytes(), &domains); err != nil {
+			t.Fatalf("GET %s: %v: %s", path, err, recorder.Body)
+		}
+		paged = append(paged, domains...)
+
+		path = ""
+		if link := recorder.Header().Get("Link"); link != "" {
+			path = link[1:strings.Index(link, ">")]
+		}
+	}
+	if !reflect.DeepEqual(paged, want) {
+		t.Errorf("paged = %v, want %v", paged, want)
+	}
+
+	// the envelope counts every domain, not just those of the page
+	var envelope struct {
+		Results []string `json:"results"`
+		Total   int64    `json:"total"`
+		Next    string   `json:"next"`
+	}
+	if code := get(t, router, "/tlds/example.com?limit=1&envelope=true", &envelope); code != http.StatusOK {
+		t.Errorf("GET /tlds/example.com?envelope=true = %d", code)
+	}
+	if !reflect.DeepEqual(envelope.Results, want[:1]) || envelope.Total != 3 || envelope.Next == "" {
+		t.Errorf("GET /tlds/example.com?envelope=true = %+v, want %v of 3 with a next cursor", envelope, want[:1])
+	}
+
+	var count struct {
+		Count int64 `json:"count"`
+	}
+	if code := get(t, router, "/count/tlds/example.com", &count); code != http.StatusOK || count.Count != 3 {
+		t.Errorf("GET /count/tlds/example.com = %d, %d, want 3", code, count.Count)
+	}
+}
+
+func TestDebugVarsNotServed(t *testing.T) {
+	router := testRouter(t, map[string][]string{search.DomainDataset: {"example,com,www,1.2.3.4"}})
+
+	// the scheduler's stats are only served on the admin listener
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("GET /debug/vars = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
+
+func TestReverseDNSCIDRInvalidMask(t *testing.T) {
+	router := testRouter(t, map[string][]string{search.ReverseDataset: {"16909060,www.example.com"}})
+
+	// bad masks are rejected rather than read as /0, which would scan the
+	// whole dataset
+	for _, path := range []string{"/reverse/1.2.3.0/abc", "/reverse/1.2.3.4/33", "/reverse/1.2.3.4/-1", "/count/reverse/1.2.3.0/abc"} {
+		var response map[string]interface{}
+		if code := get(t, router, path, &response); code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want %d", path, code, http.StatusBadRequest)
+		}
+	}
+}
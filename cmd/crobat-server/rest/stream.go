@@ -0,0 +1,180 @@
+package rest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/cgboal/sonarsearch/pkg/output"
+	"github.com/cgboal/sonarsearch/pkg/search"
+	"github.com/gin-gonic/gin"
+)
+
+// Formats which results can be streamed in
+const (
+	ndjsonFormat = "ndjson"
+	csvFormat    = "csv"
+)
+
+// errorTrailer and nextTrailer are sent after a stream, once it is known
+// whether the search failed and where it left off.
+const (
+	errorTrailer = "X-Crobat-Error"
+	nextTrailer  = "X-Crobat-Next-Cursor"
+)
+
+// streamFlushRows is how many rows are written between flushes, so that
+// clients see results as they are found without a write for every row.
+const streamFlushRows = 256
+
+// streamFormat returns the format asked for with ?format=, or else the
+// Accept header. An empty format means the results are returned as a single
+// JSON document, as before.
+func streamFormat(c *gin.Context) (string, error) {
+	switch format := c.Query("format"); format {
+	case "":
+	case "json":
+		return "", nil
+	case ndjsonFormat, csvFormat:
+		return format, nil
+	default:
+		return "", &search.QueryError{Reason: "format must be either 'json', 'ndjson' or 'csv', got " + format}
+	}
+
+	switch c.NegotiateFormat(gin.MIMEJSON, "application/x-ndjson", "application/ndjson", "text/csv") {
+	case "application/x-ndjson", "application/ndjson":
+		return ndjsonFormat, nil
+	case "text/csv":
+		return csvFormat, nil
+	default:
+		return "", nil
+	}
+}
+
+// stream writes the results of searcher as they are found, rather than
+// collecting a page of them first, when the request asks for a stream. It
+// reports whether it responded. row returns the fields of the current
+// result, named by fields, or nil to leave it out. Streams are only bounded
+// by an explicit ?limit=.
+func stream(c *gin.Context, searcher search.Searcher, fields []string, row func() []interface{}) bool {
+	format, err := streamFormat(c)
+	if err != nil {
+		searchError(c, err)
+		return true
+	}
+	if format == "" {
+		return false
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil {
+		limit = 0
+	}
+
+	writer := &rowWriter{c: c, format: format, fields: fields}
+	for limit <= 0 || writer.rows < limit {
+		if !searcher.Next() {
+			break
+		}
+		if values := row(); values != nil {
+			writer.write(values)
+		}
+	}
+
+	var next *search.Cursor
+	if limit > 0 && writer.rows == limit {
+		next = search.NextCursor(searcher)
+	}
+
+	err = searcher.Error()
+	if err == io.EOF {
+		err = nil
+	}
+	if err != nil && !writer.started {
+		// nothing has been written, so the error can still be the response
+		searchError(c, err)
+		return true
+	}
+
+	writer.finish(next, err)
+	return true
+}
+
+// rowWriter writes rows as NDJSON objects or CSV records. The response is
+// only started once there is a row to write, so that searches which fail
+// straight away respond with an error status.
+type rowWriter struct {
+	c       *gin.Context
+	format  string
+	fields  []string
+	csv     *csv.Writer
+	started bool
+	rows    int
+}
+
+func (rw *rowWriter) start() {
+	rw.started = true
+
+	header := rw.c.Writer.Header()
+	header.Set("Trailer", errorTrailer+", "+nextTrailer)
+	if rw.format == csvFormat {
+		header.Set("Content-Type", "text/csv; charset=utf-8")
+		rw.csv = csv.NewWriter(rw.c.Writer)
+		rw.csv.Write(rw.fields)
+	} else {
+		header.Set("Content-Type", "application/x-ndjson")
+	}
+	rw.c.Status(http.StatusOK)
+}
+
+func (rw *rowWriter) write(values []interface{}) {
+	if !rw.started {
+		rw.start()
+	}
+
+	if rw.format == csvFormat {
+		record := make([]string, len(values))
+		for i, value := range values {
+			record[i] = output.Text(value)
+		}
+		rw.csv.Write(record)
+	} else {
+		rw.c.Writer.Write(append(output.JSONObject(rw.fields, values), '\n'))
+	}
+
+	rw.rows++
+	if rw.rows == 1 || rw.rows%streamFlushRows == 0 {
+		rw.flush()
+	}
+}
+
+// finish ends the stream, reporting where it left off and any error which
+// cut it short. NDJSON streams also end with the error as their last line.
+func (rw *rowWriter) finish(next *search.Cursor, err error) {
+	if !rw.started {
+		rw.start()
+	}
+
+	if err != nil {
+		_, code := errorStatus(err)
+		if rw.format == ndjsonFormat {
+			line, _ := json.Marshal(gin.H{"error": err.Error(), "code": code})
+			rw.c.Writer.Write(append(line, '\n'))
+		}
+		rw.c.Writer.Header().Set(errorTrailer, code)
+	}
+	if next != nil {
+		rw.c.Writer.Header().Set(nextTrailer, next.String())
+	}
+
+	rw.flush()
+}
+
+func (rw *rowWriter) flush() {
+	if rw.csv != nil {
+		rw.csv.Flush()
+	}
+	rw.c.Writer.Flush()
+}
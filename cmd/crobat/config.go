@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// ClientConfig holds the settings used to reach a crobat server.
+type ClientConfig struct {
+	Endpoint string
+	// Data is a directory of datasets to query in this process, instead of
+	// a server
+	Data string
+	// Plaintext dials the server without TLS, as crobat-server serves by
+	// default
+	Plaintext bool
+	// Insecure skips verifying the server's certificate
+	Insecure   bool
+	ServerName string
+	CAFile     string
+	// CertFile and KeyFile hold the client certificate, for servers which
+	// require mutual TLS
+	CertFile string
+	KeyFile  string
+	// Token is sent as a bearer token with every request, for servers behind
+	// an authenticating proxy
+	Token string
+}
+
+// connectionFlags are the flags which may also be set with CROBAT_ env vars
+// or in the config file, under the same name with underscores.
+var connectionFlags = map[string]string{
+	"endpoint":    "Address of the crobat server, such as localhost:1997",
+	"data":        "Directory of datasets to query locally instead of a server, such as one holding domain and reverse",
+	"plaintext":   "Connect to the server without TLS",
+	"insecure":    "Skip verifying the server's TLS certificate",
+	"server-name": "Name to verify the server's TLS certificate against, if not that of the endpoint",
+	"ca-file":     "PEM file of the CA certificates to verify the server with, instead of the system roots",
+	"cert-file":   "PEM file of the client certificate, for servers which require mutual TLS",
+	"key-file":    "PEM file of the client certificate's key",
+	"token":       "Token sent as a bearer token in the authorization header of every request",
+}
+
+func registerConnectionFlags() *string {
+	for name, usage := range connectionFlags {
+		if name == "plaintext" || name == "insecure" {
+			flag.Bool(name, false, usage)
+		} else {
+			flag.String(name, "", usage)
+		}
+	}
+
+	return flag.String("config", "", "Config file to read connection settings from (default $XDG_CONFIG_HOME/crobat/config.yaml)")
+}
+
+// loadClientConfig reads the connection settings from flags, CROBAT_ env
+// vars and the config file, in that order of precedence. It must be called
+// once the flags have been parsed.
+func loadClientConfig(configFile string) (ClientConfig, error) {
+	viper.SetEnvPrefix("crobat")
+	viper.AutomaticEnv()
+
+	if configFile == "" {
+		configFile = os.Getenv("CROBAT_CONFIG")
+	}
+	if configFile != "" {
+		viper.SetConfigFile(configFile)
+	} else {
+		viper.SetConfigName("config")
+		if dir, err := os.UserConfigDir(); err == nil {
+			viper.AddConfigPath(filepath.Join(dir, "crobat"))
+		}
+	}
+
+	if err := viper.ReadInConfig(); err != nil {
+		// the default config file is optional, but one which was asked for is not
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return ClientConfig{}, fmt.Errorf("reading config file: %w", err)
+		}
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		if _, exists := connectionFlags[f.Name]; exists {
+			viper.Set(strings.ReplaceAll(f.Name, "-", "_"), f.Value.String())
+		}
+	})
+
+	config := ClientConfig{
+		Endpoint:   viper.GetString("endpoint"),
+		Data:       viper.GetString("data"),
+		Plaintext:  viper.GetBool("plaintext"),
+		Insecure:   viper.GetBool("insecure"),
+		ServerName: viper.GetString("server_name"),
+		CAFile:     viper.GetString("ca_file"),
+		CertFile:   viper.GetString("cert_file"),
+		KeyFile:    viper.GetString("key_file"),
+		Token:      viper.GetString("token"),
+	}
+
+	if config.Data != "" {
+		// local datasets take the place of the server
+		return config, nil
+	}
+	if config.Endpoint == "" {
+		return config, errors.New("no endpoint is configured, set one with -endpoint, CROBAT_ENDPOINT or endpoint in the config file, or query local datasets with -data")
+	}
+	if config.Plaintext && (config.Insecure || config.ServerName != "" || config.CAFile != "" || config.CertFile != "") {
+		return config, errors.New("TLS settings cannot be used with plaintext")
+	}
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return config, errors.New("cert_file and key_file must be set together")
+	}
+
+	return config, nil
+}
+
+// dialOptions returns the options used to dial the server described by
+// config.
+func (config ClientConfig) dialOptions() ([]grpc.DialOption, error) {
+	var options []grpc.DialOption
+	if config.Plaintext {
+		options = append(options, grpc.WithInsecure())
+	} else {
+		tlsConfig, err := config.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	}
+
+	if config.Token != "" {
+		options = append(options, grpc.WithPerRPCCredentials(tokenCredentials{
+			token:  config.Token,
+			secure: !config.Plaintext,
+		}))
+	}
+
+	return options, nil
+}
+
+func (config ClientConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         config.ServerName,
+		InsecureSkipVerify: config.Insecure,
+	}
+
+	if config.CAFile != "" {
+		ca, err := ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", config.CAFile)
+		}
+	}
+
+	if config.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// tokenCredentials sends a bearer token with every RPC.
+type tokenCredentials struct {
+	token  string
+	secure bool
+}
+
+func (tc tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + tc.token}, nil
+}
+
+// RequireTransportSecurity allows tokens to be sent in plaintext only when
+// plaintext was asked for, such as to a proxy on the same host.
+func (tc tokenCredentials) RequireTransportSecurity() bool {
+	return tc.secure
+}
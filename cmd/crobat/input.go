@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errInvalidInput is reported for inputs which are not a domain, IP address
+// or CIDR range.
+var errInvalidInput = errors.New("not a domain, IP address or CIDR range")
+
+// listExtensions are those of files holding lists of inputs, which are not
+// the TLD of any domain.
+var listExtensions = []string{".txt", ".lst", ".list", ".csv"}
+
+// ProcessArg returns the inputs given as the value of a flag, which is
+// either - to read them from stdin, the path of a file holding them, or a
+// quoted list separated by spaces. Prefixing a path with @ requires it to be
+// a file. Inputs are separated by whitespace, and everything after a # on a
+// line is a comment.
+func ProcessArg(arg string) ([]string, error) {
+	if arg == "-" {
+		return readInputs(os.Stdin)
+	}
+	if strings.HasPrefix(arg, "@") {
+		return readFile(arg[1:])
+	}
+
+	file, err := os.Open(arg)
+	if err == nil {
+		defer file.Close()
+		return readInputs(file)
+	}
+
+	inputs := strings.Fields(arg)
+	if len(inputs) != 1 {
+		return inputs, nil
+	}
+
+	// a single value which looks like a path was meant to be a file, and so
+	// was one which is not a valid input
+	kind := inputKind(arg)
+	if kind == "" || looksLikePath(arg, kind) {
+		return nil, err
+	}
+	if kind == "domain" && !strings.Contains(arg, ".") {
+		fmt.Fprintf(os.Stderr, "%s was not found as a file, querying it as a domain\n", arg)
+	}
+
+	return inputs, nil
+}
+
+func readFile(fileName string) ([]string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readInputs(file)
+}
+
+// looksLikePath reports whether arg, an input of the given kind, holds a
+// path separator or ends in the extension of a list.
+func looksLikePath(arg string, kind string) bool {
+	if kind != "cidr" && strings.ContainsAny(arg, "/"+string(filepath.Separator)) {
+		return true
+	}
+
+	ext := strings.ToLower(filepath.Ext(arg))
+	for _, listExt := range listExtensions {
+		if ext == listExt {
+			return true
+		}
+	}
+
+	return false
+}
+
+func readInputs(r io.Reader) ([]string, error) {
+	var inputs []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if comment := strings.IndexByte(line, '#'); comment >= 0 {
+			line = line[:comment]
+		}
+		inputs = append(inputs, strings.Fields(line)...)
+	}
+
+	return inputs, scanner.Err()
+}
+
+// inputKind returns whether input is a domain, ip or cidr, or an empty
+// string if it is none of them.
+func inputKind(input string) string {
+	if net.ParseIP(input) != nil {
+		return "ip"
+	}
+	if _, _, err := net.ParseCIDR(input); err == nil {
+		return "cidr"
+	}
+
+	labels := strings.Split(strings.TrimSuffix(input, "."), ".")
+	for _, label := range labels {
+		if label == "" {
+			return ""
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return ""
+			}
+		}
+	}
+
+	return "domain"
+}
+
+// inputQueries returns the query for each input, which is a reverse lookup
+// for IP addresses and CIDR ranges, and a query of domainType for domains.
+// Inputs which are neither are returned as invalid.
+func inputQueries(domainType string, inputs []string) (queries []Query, invalid []Query) {
+	for _, input := range inputs {
+		switch inputKind(input) {
+		case "ip", "cidr":
+			queries = append(queries, Query{Type: "reverse", Query: input})
+		case "domain":
+			queries = append(queries, Query{Type: domainType, Query: strings.TrimSuffix(input, ".")})
+		default:
+			invalid = append(invalid, Query{Type: domainType, Query: input})
+		}
+	}
+
+	return queries, invalid
+}
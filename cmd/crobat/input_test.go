@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProcessArg(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "scope.txt")
+	if err := os.WriteFile(fileName, []byte("# in scope\nexample.com 203.0.113.0/24\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		arg     string
+		inputs  []string
+		invalid bool
+	}{
+		{arg: fileName, inputs: []string{"example.com", "203.0.113.0/24"}},
+		{arg: "@" + fileName, inputs: []string{"example.com", "203.0.113.0/24"}},
+		{arg: "example.com example.co.uk", inputs: []string{"example.com", "example.co.uk"}},
+		{arg: "example.com", inputs: []string{"example.com"}},
+		{arg: "203.0.113.0/24", inputs: []string{"203.0.113.0/24"}},
+		// missing files are reported rather than queried as domains
+		{arg: "scope.txt", invalid: true},
+		{arg: "lists/scope", invalid: true},
+		{arg: "@example.com", invalid: true},
+		{arg: "not a/file", inputs: []string{"not", "a/file"}},
+	}
+
+	for _, test := range tests {
+		inputs, err := ProcessArg(test.arg)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("ProcessArg(%q) = %v, want an error", test.arg, inputs)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(inputs, test.inputs) {
+			t.Errorf("ProcessArg(%q) = %v, %v, want %v", test.arg, inputs, err, test.inputs)
+		}
+	}
+}
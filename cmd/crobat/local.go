@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"io"
+
+	"github.com/cgboal/sonarsearch/pkg/grpcstatus"
+	"github.com/cgboal/sonarsearch/pkg/search"
+	crobat "github.com/cgboal/sonarsearch/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// localClient runs queries against the datasets in a directory by calling
+// the searches directly, rather than through a server. Results carry the
+// same cursors that crobat-server would send.
+type localClient struct {
+	catalog *search.Catalog
+}
+
+func openLocal(dir string) (*localClient, error) {
+	catalog, err := search.OpenDirectory(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &localClient{catalog: catalog}, nil
+}
+
+func (lc *localClient) stream(ctx context.Context, query Query) (domainStream, error) {
+	cursor, err := localCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	switch query.Type {
+	case "subdomains":
+		domains, err := lc.catalog.Dataset(search.DomainDataset)
+		if err != nil {
+			return nil, grpcstatus.FromSearch(err)
+		}
+		// without the zone dataset, sub-zones are filtered from the domain
+		// dataset
+		zones, _ := lc.catalog.Dataset(search.ZoneDataset)
+
+		searcher, err := search.NewSubzoneSearch(ctx, domains, zones, query.Query, cursor)
+		if err != nil {
+			return nil, grpcstatus.FromSearch(err)
+		}
+		return &localStream{searcher: searcher, reply: func() *crobat.Domain {
+			return &crobat.Domain{Domain: searcher.Text(), Ip: searcher.Value(), Ips: searcher.Values()}
+		}}, nil
+	case "tlds":
+		dataset, err := lc.catalog.Dataset(search.DomainDataset)
+		if err != nil {
+			return nil, grpcstatus.FromSearch(err)
+		}
+
+		searcher, err := search.NewTLDSearch(ctx, dataset, query.Query, cursor)
+		if err != nil {
+			return nil, grpcstatus.FromSearch(err)
+		}
+		return &localStream{searcher: searcher, reply: func() *crobat.Domain {
+			return &crobat.Domain{Domain: searcher.Text()}
+		}}, nil
+	case "suffix":
+		dataset, err := lc.catalog.Dataset(search.SuffixDataset)
+		if err != nil {
+			return nil, grpcstatus.FromSearch(err)
+		}
+
+		searcher, err := search.NewSuffixSearch(ctx, dataset, query.Query, cursor)
+		if err != nil {
+			return nil, grpcstatus.FromSearch(err)
+		}
+		return &localStream{searcher: searcher, reply: func() *crobat.Domain {
+			return &crobat.Domain{Domain: searcher.Text(), SubdomainCount: int64(searcher.Subdomains())}
+		}}, nil
+	case "reverse":
+		dataset, err := lc.catalog.ReverseDataset(query.Query)
+		if err != nil {
+			return nil, grpcstatus.FromSearch(err)
+		}
+
+		searcher, err := search.NewReverseSearch(ctx, dataset, query.Query, cursor)
+		if err != nil {
+			return nil, grpcstatus.FromSearch(err)
+		}
+		return &localStream{searcher: searcher, reply: func() *crobat.Domain {
+			result := searcher.Result()
+			return &crobat.Domain{Domain: result.Domain, Ip: result.IP}
+		}}, nil
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown query type %s", query.Type)
+	}
+}
+
+func localCursor(token string) (*search.Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	cursor, err := search.ParseCursor(token)
+	if err != nil {
+		return nil, grpcstatus.FromSearch(err)
+	}
+
+	return cursor, nil
+}
+
+// localStream returns the results of a search one at a time, as a stream
+// from crobat-server would.
+type localStream struct {
+	searcher search.Searcher
+	// reply returns the current result of searcher
+	reply func() *crobat.Domain
+	done  bool
+}
+
+func (ls *localStream) Recv() (*crobat.Domain, error) {
+	// searches for a single name or address may reach the end of the dataset
+	// along with their last result
+	if !ls.done && ls.searcher.Next() {
+		ls.done = ls.searcher.Error() == io.EOF
+		reply := ls.reply()
+		reply.Cursor = ls.searcher.Cursor().String()
+		return reply, nil
+	}
+
+	err := ls.searcher.Error()
+	ls.searcher.Close()
+	if err == nil || err == io.EOF {
+		return nil, io.EOF
+	}
+
+	return nil, grpcstatus.FromSearch(err)
+}
@@ -1,25 +1,23 @@
 package main
 
 import (
-	"bufio"
 	"context"
-	"crypto/tls"
 	"flag"
-	"fmt"
 	crobat "github.com/cgboal/sonarsearch/proto"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	"io"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"log"
 	"os"
-	"path/filepath"
-	"strings"
+	"os/signal"
 	"sync"
 )
 
 type CrobatClient struct {
 	conn   *grpc.ClientConn
 	client crobat.CrobatClient
+	// local runs queries against datasets on disk instead, when set
+	local *localClient
 }
 
 type UniqueStringSlice map[string]struct{}
@@ -32,199 +30,157 @@ func (slice *UniqueStringSlice) Append(value string) bool {
 	return false
 }
 
-func ProcessArg(arg string) (args []string) {
-	dir, err := filepath.Abs(filepath.Dir(os.Args[0]))
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	fileName := fmt.Sprintf("%s/%s", dir, arg)
-	if _, err := os.Stat(fileName); err == nil {
-		file, _ := os.Open(fileName)
-		defer file.Close()
-		scanner := bufio.NewScanner(file)
-
-		for scanner.Scan() {
-			args = append(args, scanner.Text())
+func NewCrobatClient(config ClientConfig) (CrobatClient, error) {
+	if config.Data != "" {
+		local, err := openLocal(config.Data)
+		if err != nil {
+			return CrobatClient{}, err
 		}
-	} else {
-		args = strings.Split(arg, " ")
+
+		return CrobatClient{local: local}, nil
 	}
 
-	return args
-}
+	options, err := config.dialOptions()
+	if err != nil {
+		return CrobatClient{}, err
+	}
 
-func NewCrobatClient() CrobatClient {
-	config := &tls.Config{}
-	conn, err := grpc.Dial("crobat-rpc.omnisint.io:443", grpc.WithTransportCredentials(credentials.NewTLS(config)))
+	conn, err := grpc.Dial(config.Endpoint, options...)
 	if err != nil {
-		log.Fatal(err)
+		return CrobatClient{}, err
 	}
 
 	client := crobat.NewCrobatClient(conn)
 	return CrobatClient{
 		conn:   conn,
 		client: client,
-	}
-}
-
-func (c *CrobatClient) GetSubdomains(arg string, resultsChan chan string) {
-	args := ProcessArg(arg)
-	defer close(resultsChan)
-	for _, domain := range args {
-		query := &crobat.QueryRequest{
-			Query: domain,
-		}
-
-		stream, err := c.client.GetSubdomains(context.Background(), query)
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		for {
-			domain, err := stream.Recv()
-			if err == io.EOF {
-				break
-			}
-
-			if err != nil {
-				log.Fatal(err)
-			}
-			resultsChan <- domain.Domain
-		}
-	}
-
+	}, nil
 }
 
-func (c *CrobatClient) GetTlds(arg string, resultsChan chan string) {
-	args := ProcessArg(arg)
-	defer close(resultsChan)
-	for _, domain := range args {
+func main() {
+	domain_sub := flag.String("s", "", "Get subdomains for this value. Supports files, - for stdin and quoted lists")
+	domain_tld := flag.String("t", "", "Get tlds for this value. Supports files, - for stdin and quoted lists")
+	suffix_domains := flag.String("p", "", "List registered domains under this public suffix, such as gov.uk. Supports files, - for stdin and quoted lists")
+	suffix_counts := flag.Bool("counts", false, "Include the number of subdomains of each domain listed with -p")
+	reverse_dns := flag.String("r", "", "Perform reverse lookup on IP address or CIDR range. Supports files, - for stdin and quoted lists")
+	unique_sort := flag.Bool("u", false, "Ensures results are unique, may cause instability on large queries due to RAM requirements")
+	workers := flag.Int("c", 1, "Number of queries to run at once")
+	retries := flag.Int("retries", 3, "Number of times to retry a failed query, picking up after its last result")
+	errors_file := flag.String("errors", "", "File to write the queries which failed to, as JSON, which can be passed to -resume (default stderr)")
+	resume_file := flag.String("resume", "", "Run the queries listed in an error report written with -errors, from where they left off")
+	output_format := flag.String("o", "txt", "Output format, either txt, csv or json")
+	output_fields := flag.String("fields", "", "Comma separated fields to output, from query, domain, ip, ips, type and subdomain_count (default domain for txt, and query,domain,ip,type otherwise)")
+	config_file := registerConnectionFlags()
+
+	resultsChan := make(chan Result)
+	var wg sync.WaitGroup
 
-		query := &crobat.QueryRequest{
-			Query: domain,
-		}
+	flag.Parse()
 
-		stream, err := c.client.GetTLDs(context.Background(), query)
-		if err != nil {
-			log.Fatal(err)
+	fieldList := *output_fields
+	if fieldList == "" {
+		fieldList = "domain"
+		if *output_format != "txt" {
+			fieldList = "query,domain,ip,type"
 		}
-
-		for {
-			domain, err := stream.Recv()
-			if err == io.EOF {
-				break
-			}
-
-			if err != nil {
-				log.Fatal(err)
-			}
-			resultsChan <- domain.Domain
+		if *suffix_domains != "" && *suffix_counts {
+			fieldList += ",subdomain_count"
 		}
 	}
-
-}
-
-func (c *CrobatClient) ReverseDNS(arg string, resultsChan chan string) {
-	args := ProcessArg(arg)
-	defer close(resultsChan)
-	for _, ipv4 := range args {
-
-		query := &crobat.QueryRequest{
-			Query: ipv4,
-		}
-
-		stream, err := c.client.ReverseDNS(context.Background(), query)
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		for {
-			domain, err := stream.Recv()
-			if err == io.EOF {
-				break
-			}
-
-			if err != nil {
-				log.Fatal(err)
-			}
-			resultsChan <- domain.Domain
-		}
-
+	fields, err := parseFields(fieldList)
+	if err != nil {
+		log.Fatal(err)
 	}
-}
-
-func (c *CrobatClient) ReverseDNSRange(arg string, resultsChan chan string) {
-	args := ProcessArg(arg)
-	defer close(resultsChan)
-	for _, ipv4 := range args {
-		query := &crobat.QueryRequest{
-			Query: ipv4,
-		}
-
-		stream, err := c.client.ReverseDNSRange(context.Background(), query)
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		for {
-			domain, err := stream.Recv()
-			if err == io.EOF {
-				break
-			}
-
-			if err != nil {
-				log.Fatal(err)
-			}
-			
-			if domain == nil {
-				continue
-			}
-			resultsChan <- domain.Domain
-		}
 
+	writer, err := NewResultWriter(os.Stdout, *output_format, fields)
+	if err != nil {
+		log.Fatal(err)
 	}
-}
-
-func main() {
-	domain_sub := flag.String("s", "", "Get subdomains for this value. Supports files and quoted lists")
-	domain_tld := flag.String("t", "", "Get tlds for this value. Supports files and quoted lists")
-	reverse_dns := flag.String("r", "", "Perform reverse lookup on IP address or CIDR range. Supports files and quoted lists")
-	unique_sort := flag.Bool("u", false, "Ensures results are unique, may cause instability on large queries due to RAM requirements")
 
-	resultsChan := make(chan string)
-	var wg sync.WaitGroup
+	config, err := loadClientConfig(*config_file)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	flag.Parse()
+	client, err := NewCrobatClient(config)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		uniqueSlice := UniqueStringSlice{}
 		for result := range resultsChan {
+			line := writer.Format(result)
 			if *unique_sort {
-				if uniqueSlice.Append(result) {
-					fmt.Println(result)
+				if uniqueSlice.Append(line) {
+					writer.WriteLine(line)
 				}
 			} else {
-				fmt.Println(result)
+				writer.WriteLine(line)
 			}
 		}
 	}()
 
-	client := NewCrobatClient()
+	// IP addresses and CIDR ranges are looked up in reverse whichever flag
+	// they are given to, and domains given to -r are searched for subdomains
+	arg, domainType := *reverse_dns, "subdomains"
 	if *domain_sub != "" {
-		client.GetSubdomains(*domain_sub, resultsChan)
+		arg, domainType = *domain_sub, "subdomains"
 	} else if *domain_tld != "" {
-		client.GetTlds(*domain_tld, resultsChan)
-	} else if *reverse_dns != "" {
-		if !strings.Contains(*reverse_dns, "/") {
-			client.ReverseDNS(*reverse_dns, resultsChan)
-		} else {
-			client.ReverseDNSRange(*reverse_dns, resultsChan)
+		arg, domainType = *domain_tld, "tlds"
+	} else if *suffix_domains != "" {
+		arg, domainType = *suffix_domains, "suffix"
+	}
+
+	var queries, invalid []Query
+	if arg != "" {
+		inputs, err := ProcessArg(arg)
+		if err != nil {
+			log.Fatal(err)
 		}
+		queries, invalid = inputQueries(domainType, inputs)
 	}
+	if *resume_file != "" {
+		resumed, err := readReport(*resume_file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		queries = append(queries, resumed...)
+	}
+
+	errorsOut := os.Stderr
+	if *errors_file != "" {
+		errorsOut, err = os.Create(*errors_file)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	report := NewErrorReport(errorsOut)
+	for _, query := range invalid {
+		report.Add(query, status.Error(codes.InvalidArgument, errInvalidInput.Error()))
+	}
+
+	if *workers < 1 {
+		*workers = 1
+	}
+	runner := Runner{Client: &client, Workers: *workers, Retries: *retries, Report: report}
 
+	// an interrupted run reports the queries it did not finish, so that they
+	// can be resumed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+
+	runner.Run(ctx, queries, resultsChan)
+	close(resultsChan)
 	wg.Wait()
+	stop()
 
+	if *errors_file != "" {
+		errorsOut.Close()
+	}
+	if failed := report.Failed(); failed > 0 {
+		log.Printf("%d of %d queries failed", failed, len(queries)+len(invalid))
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cgboal/sonarsearch/pkg/output"
+	crobat "github.com/cgboal/sonarsearch/proto"
+)
+
+// Result is a single result, along with the query which produced it.
+type Result struct {
+	Query          string
+	Domain         string
+	IP             string
+	IPs            []string
+	Type           string
+	SubdomainCount int64
+}
+
+func newResult(query string, domain *crobat.Domain) Result {
+	result := Result{
+		Query:          query,
+		Domain:         domain.Domain,
+		IP:             domain.Ip,
+		IPs:            domain.Ips,
+		Type:           domain.Type,
+		SubdomainCount: domain.SubdomainCount,
+	}
+
+	// only record lookups name their type, the rest come from A and AAAA
+	// records
+	if result.Type == "" && result.IP != "" {
+		result.Type = "a"
+		if strings.Contains(result.IP, ":") {
+			result.Type = "aaaa"
+		}
+	}
+
+	return result
+}
+
+// resultFields maps the names accepted by -fields to the field of a result
+// they select.
+var resultFields = map[string]func(Result) interface{}{
+	"query":           func(r Result) interface{} { return r.Query },
+	"domain":          func(r Result) interface{} { return r.Domain },
+	"ip":              func(r Result) interface{} { return r.IP },
+	"ips":             func(r Result) interface{} { return append([]string{}, r.IPs...) },
+	"type":            func(r Result) interface{} { return r.Type },
+	"subdomain_count": func(r Result) interface{} { return r.SubdomainCount },
+}
+
+// parseFields splits a comma separated list of fields, such as
+// "query,domain,ip".
+func parseFields(list string) ([]string, error) {
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if _, exists := resultFields[field]; !exists {
+			return nil, fmt.Errorf("unknown field %q, fields must be query, domain, ip, ips, type or subdomain_count", field)
+		}
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// ResultWriter writes results in one of the output formats, with only the
+// fields asked for.
+type ResultWriter struct {
+	w      io.Writer
+	format string
+	fields []string
+}
+
+// NewResultWriter returns a writer for format, which must be txt, csv or
+// json. CSV output starts with a header naming the fields, and JSON output
+// holds an object on each line.
+func NewResultWriter(w io.Writer, format string, fields []string) (*ResultWriter, error) {
+	rw := &ResultWriter{w: w, format: format, fields: fields}
+	switch format {
+	case "txt", "json":
+	case "csv":
+		if err := rw.WriteLine(csvLine(fields)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("output format must be either 'txt', 'csv' or 'json', got %s", format)
+	}
+
+	return rw, nil
+}
+
+// Format returns result as it would be written.
+func (rw *ResultWriter) Format(result Result) string {
+	values := make([]interface{}, len(rw.fields))
+	for i, field := range rw.fields {
+		values[i] = resultFields[field](result)
+	}
+
+	switch rw.format {
+	case "json":
+		return string(output.JSONObject(rw.fields, values))
+	case "csv":
+		return csvLine(textFields(values))
+	default:
+		// fields are separated by commas, as -counts always has been
+		return strings.Join(textFields(values), ",")
+	}
+}
+
+// WriteLine writes a line returned by Format.
+func (rw *ResultWriter) WriteLine(line string) error {
+	_, err := fmt.Fprintln(rw.w, line)
+	return err
+}
+
+func (rw *ResultWriter) Write(result Result) error {
+	return rw.WriteLine(rw.Format(result))
+}
+
+func csvLine(record []string) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write(record)
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// textFields formats values as text, with lists separated by spaces.
+func textFields(values []interface{}) []string {
+	fields := make([]string, len(values))
+	for i, value := range values {
+		fields[i] = output.Text(value)
+	}
+
+	return fields
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	crobat "github.com/cgboal/sonarsearch/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Backoff between retries of a failed query, which doubles with each retry
+const (
+	minBackoff = 500 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+// Query is a single query to run, such as the subdomains of a domain.
+type Query struct {
+	// Type is either subdomains, tlds, suffix or reverse
+	Type  string `json:"type"`
+	Query string `json:"query"`
+	// Cursor resumes the query after the last result which was received
+	Cursor string `json:"cursor,omitempty"`
+}
+
+type domainStream interface {
+	Recv() (*crobat.Domain, error)
+}
+
+func (c *CrobatClient) stream(ctx context.Context, query Query) (domainStream, error) {
+	if c.local != nil {
+		return c.local.stream(ctx, query)
+	}
+
+	request := &crobat.QueryRequest{Query: query.Query, Cursor: query.Cursor}
+	switch query.Type {
+	case "subdomains":
+		return c.client.GetSubdomains(ctx, request)
+	case "tlds":
+		return c.client.GetTLDs(ctx, request)
+	case "suffix":
+		return c.client.GetSuffixDomains(ctx, request)
+	case "reverse":
+		if strings.Contains(query.Query, "/") {
+			return c.client.ReverseDNSRange(ctx, request)
+		}
+		return c.client.ReverseDNS(ctx, request)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown query type %s", query.Type)
+	}
+}
+
+// runOnce runs query, passing each result to results, and moves its cursor
+// past each result as it arrives.
+func (c *CrobatClient) runOnce(ctx context.Context, query *Query, results chan<- Result) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := c.stream(ctx, *query)
+	if err != nil {
+		return err
+	}
+
+	for {
+		domain, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if domain == nil {
+			continue
+		}
+
+		results <- newResult(query.Query, domain)
+		if domain.Cursor != "" {
+			query.Cursor = domain.Cursor
+		}
+	}
+}
+
+// retryable reports whether a query which failed with err may succeed if it
+// is run again.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted, codes.Internal, codes.Unknown:
+		return true
+	default:
+		return false
+	}
+}
+
+// Runner runs queries in parallel, retrying those which fail.
+type Runner struct {
+	Client *CrobatClient
+	// Workers bounds how many queries run at once
+	Workers int
+	// Retries is how many times a failed query is run again, picking up
+	// after the last result it returned
+	Retries int
+	// Report records the queries which failed for good
+	Report *ErrorReport
+}
+
+// Run runs every query, passing their results to results. Queries which
+// fail are added to the report rather than stopping the others. Once ctx is
+// done, queries which have not finished are reported as canceled, so that
+// they can be resumed.
+func (r *Runner) Run(ctx context.Context, queries []Query, results chan<- Result) {
+	pending := make(chan Query)
+	var wg sync.WaitGroup
+	for i := 0; i < r.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for query := range pending {
+				if err := r.run(ctx, &query, results); err != nil {
+					r.Report.Add(query, err)
+				}
+			}
+		}()
+	}
+
+	for i, query := range queries {
+		select {
+		case pending <- query:
+			continue
+		case <-ctx.Done():
+		}
+
+		for _, query := range queries[i:] {
+			r.Report.Add(query, status.FromContextError(ctx.Err()).Err())
+		}
+		break
+	}
+	close(pending)
+	wg.Wait()
+}
+
+func (r *Runner) run(ctx context.Context, query *Query, results chan<- Result) error {
+	backoff := minBackoff
+	for attempt := 0; ; attempt++ {
+		err := r.Client.runOnce(ctx, query, results)
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		// queries without results have not failed
+		if err == nil || status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if attempt >= r.Retries || !retryable(err) {
+			return err
+		}
+
+		// jitter keeps the workers from retrying in step
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// ErrorReport records the queries which failed as JSON, one per line, along
+// with the cursor of the last result they returned. The report can be passed
+// to -resume to run them again from where they left off.
+type ErrorReport struct {
+	mu     sync.Mutex
+	w      io.Writer
+	failed int
+}
+
+type reportLine struct {
+	Query
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+func NewErrorReport(w io.Writer) *ErrorReport {
+	return &ErrorReport{w: w}
+}
+
+func (er *ErrorReport) Add(query Query, err error) {
+	st := status.Convert(err)
+	line, _ := json.Marshal(reportLine{Query: query, Code: st.Code().String(), Error: st.Message()})
+
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	er.failed++
+	er.w.Write(append(line, '\n'))
+}
+
+// Failed returns how many queries have been reported.
+func (er *ErrorReport) Failed() int {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	return er.failed
+}
+
+// readReport returns the queries listed in an error report.
+func readReport(fileName string) ([]Query, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var queries []Query
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		var failed reportLine
+		if err := json.Unmarshal(scanner.Bytes(), &failed); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", fileName, line, err)
+		}
+		queries = append(queries, failed.Query)
+	}
+
+	return queries, scanner.Err()
+}
@@ -8,12 +8,67 @@ import (
 	"io"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/cgboal/sonarsearch/pkg/ipconv"
 )
 
 type KeyFunc func(line string) string 
 
+// reverseBucketKey records the prefix length of the reverse index buckets,
+// and must match the key read by pkg/search.
+const reverseBucketKey = "crobat:reverse:bucket"
+
+const reverse6BucketKey = "crobat:reverse6:bucket"
+
+// reverse6KeyPrefix keeps IPv6 keys apart from IPv4 ones in shared backends
+const reverse6KeyPrefix = "reverse6:"
+
+type WriterFunc func(key string, value string)
+
+// GroupFunc returns what identifies a result in a line, so that consecutive
+// lines belonging to the same result are counted once. Formats without one
+// count every line.
+type GroupFunc func(line string) string
+
+func redisWriter(key string, value string) {
+	fmt.Printf("*3\r\n$3\r\nSET\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(key), key, len(value), value)
+}
+
+// postgresWriter emits rows for `COPY crobat_index(key, value) FROM stdin (Delimiter ',')`
+func postgresWriter(key string, value string) {
+	fmt.Printf("%s,%s\n", key, value)
+}
+
+// newFileWriter emits an index file which crobat-server can open directly.
+// Lookups binary search the file, so keys must be written in sorted order.
+func newFileWriter(order string, meta map[string]string) WriterFunc {
+	fmt.Printf("#order,%s\n", order)
+	for key, value := range meta {
+		fmt.Printf("#%s,%s\n", key, value)
+	}
+
+	lastKey := ""
+	return func(key string, value string) {
+		if lastKey != "" && !keyInOrder(order, lastKey, key) {
+			fmt.Fprintf(os.Stderr, "input is not sorted, %s appears after %s. Sort the dataset with LC_ALL=C\n", key, lastKey)
+			os.Exit(1)
+		}
+		lastKey = key
+		fmt.Printf("%s,%s\n", key, value)
+	}
+}
+
+func keyInOrder(order string, previous string, key string) bool {
+	if order == "numeric" {
+		previousInt, _ := strconv.ParseUint(previous, 10, 64)
+		keyInt, _ := strconv.ParseUint(key, 10, 64)
+		return previousInt < keyInt
+	}
+
+	return previous < key
+}
+
 func getReader(fileName string) (*bufio.Reader, error) {
 	inputFile, err := os.Open(fileName)
 	if err != nil {
@@ -28,6 +83,53 @@ func domainKey(entry string) string {
 	return entry
 }
 
+// nameGroup groups the lines of the domain and zone datasets by name, as a
+// name has a line for each address it resolved to.
+func nameGroup(line string) string {
+	return firstFields(line, 3)
+}
+
+// suffixGroup groups the lines of the suffix dataset by registered domain.
+func suffixGroup(line string) string {
+	return firstFields(line, 2)
+}
+
+func firstFields(line string, n int) string {
+	fields := 0
+	for i := 0; i < len(line); i++ {
+		if line[i] == ',' {
+			fields++
+			if fields == n {
+				return line[:i]
+			}
+		}
+	}
+
+	return strings.TrimRight(line, "\n")
+}
+
+// recordFormats lists the record datasets written by sonar2crobat, each of
+// which may also be suffixed with _reverse
+var recordFormats = []string{"cname", "ns", "mx"}
+
+func isRecordFormat(format string) bool {
+	for _, recordFormat := range recordFormats {
+		if strings.TrimSuffix(format, "_reverse") == recordFormat {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newRecordKey namespaces the keys of record datasets such as cname, so that
+// they do not collide with the domain index in shared backends.
+func newRecordKey(dataset string) KeyFunc {
+	return func(entry string) string {
+		return dataset + ":" + entry
+	}
+}
+
 func reverseKey(entry string) string {
 	entryInt, _ := strconv.ParseUint(entry, 10, 32)
 
@@ -35,7 +137,33 @@ func reverseKey(entry string) string {
 	return fmt.Sprintf("%d", key)
 }
 
-func generateIndex(keyFunc KeyFunc, inputFileName string) error {
+// newPrefixReverseKey buckets IPs by network prefix rather than by decimal
+// rounding, so that CIDR queries start exactly on a bucket.
+func newPrefixReverseKey(prefixLen uint32) KeyFunc {
+	return func(entry string) string {
+		entryInt, _ := strconv.ParseUint(entry, 10, 32)
+
+		key := ipconv.PrefixBucket(uint32(entryInt), prefixLen)
+		return fmt.Sprintf("%d", key)
+	}
+}
+
+// newReverse6Key buckets the hex encoded IPv6 addresses by prefix, which
+// must be a multiple of 4 bits so that the buckets are whole hex digits.
+func newReverse6Key(prefixLen uint) KeyFunc {
+	return func(entry string) string {
+		if len(entry) < int(prefixLen/4) {
+			return reverse6KeyPrefix + entry
+		}
+
+		return reverse6KeyPrefix + entry[:prefixLen/4]
+	}
+}
+
+// generateIndex writes the offset of the first line of each key, along with
+// the offset just past its last line and how many results it holds, as
+// `start:end:count`.
+func generateIndex(keyFunc KeyFunc, groupFunc GroupFunc, writerFunc WriterFunc, inputFileName string) error {
 	reader, err := getReader(inputFileName)
 	if err != nil {
 		return err
@@ -43,6 +171,9 @@ func generateIndex(keyFunc KeyFunc, inputFileName string) error {
 
 	pos := int64(0)
 	currentKey := ""
+	currentGroup := ""
+	start := int64(0)
+	count := 0
 
 	for {
 		line, err := reader.ReadBytes('\n')
@@ -52,9 +183,20 @@ func generateIndex(keyFunc KeyFunc, inputFileName string) error {
 			entry := string(line[:delimPos])
 			key := keyFunc(entry)
 			if key != currentKey {
-				posString := fmt.Sprint(pos)
-				fmt.Printf("*3\r\n$3\r\nSET\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(key), key, len(posString), posString)
+				if currentKey != "" {
+					writerFunc(currentKey, fmt.Sprintf("%d:%d:%d", start, pos, count))
+				}
 				currentKey = key
+				currentGroup = ""
+				start = pos
+				count = 0
+			}
+
+			if groupFunc == nil {
+				count++
+			} else if group := groupFunc(string(line)); group != currentGroup {
+				currentGroup = group
+				count++
 			}
 		}
 
@@ -65,13 +207,19 @@ func generateIndex(keyFunc KeyFunc, inputFileName string) error {
 		}
 	}
 
+	if currentKey != "" {
+		writerFunc(currentKey, fmt.Sprintf("%d:%d:%d", start, pos, count))
+	}
+
 	return nil
 
 }
 
 func main() {
 	inputFileName := flag.String("i", "", "file path for raw sonar dataset")
-	format := flag.String("f", "", "what output format to use, can be 'domain' or 'reverse'")
+	format := flag.String("f", "", "what output format to use, can be 'domain', 'zone', 'suffix', 'reverse', 'reverse6', or one of 'cname', 'ns' and 'mx' optionally suffixed with '_reverse'")
+	backend := flag.String("backend", "redis", "what storage backend to write the index for, can be 'redis', 'postgres' or 'file'")
+	bucket := flag.Uint("bucket", 0, "prefix length to bucket the reverse index by, such as 24. Defaults to the legacy decimal buckets for 'reverse', and 48 for 'reverse6'")
 
 	flag.Parse()
 
@@ -80,13 +228,67 @@ func main() {
 	}
 
 	var keyFunc KeyFunc
+	var groupFunc GroupFunc
+	var order string
+	meta := map[string]string{}
 	if *format == "domain" {
 		keyFunc = domainKey
+		groupFunc = nameGroup
+		order = "lexical"
 	} else if *format == "reverse" {
 		keyFunc = reverseKey
+		order = "numeric"
+		if *bucket > 32 {
+			fmt.Printf("Bucket must be a prefix length between 1 and 32, got %d\n", *bucket)
+			os.Exit(1)
+		} else if *bucket != 0 {
+			keyFunc = newPrefixReverseKey(uint32(*bucket))
+			meta[reverseBucketKey] = fmt.Sprint(*bucket)
+		}
+	} else if *format == "reverse6" {
+		if *bucket == 0 {
+			*bucket = 48
+		}
+		if *bucket > 128 || *bucket%4 != 0 {
+			fmt.Printf("Bucket must be a multiple of 4 between 4 and 128, got %d\n", *bucket)
+			os.Exit(1)
+		}
+		keyFunc = newReverse6Key(*bucket)
+		order = "lexical"
+		meta[reverse6BucketKey] = fmt.Sprint(*bucket)
+	} else if isRecordFormat(*format) || *format == "zone" || *format == "suffix" {
+		keyFunc = newRecordKey(*format)
+		order = "lexical"
+		if *format == "zone" {
+			groupFunc = nameGroup
+		} else if *format == "suffix" {
+			groupFunc = suffixGroup
+		}
+	} else {
+		fmt.Println("Format must be either 'domain', 'zone', 'suffix', 'reverse', 'reverse6', or one of 'cname', 'ns' and 'mx' optionally suffixed with '_reverse', got " + *format)
+		os.Exit(1)
+	}
+
+	var writerFunc WriterFunc
+	if *backend == "redis" {
+		writerFunc = redisWriter
+	} else if *backend == "postgres" {
+		writerFunc = postgresWriter
+	} else if *backend == "file" {
+		writerFunc = newFileWriter(order, meta)
 	} else {
-		fmt.Println("Format must be either 'domain' or 'reverse', got " + *format)
+		fmt.Println("Backend must be either 'redis', 'postgres' or 'file', got " + *backend)
+		os.Exit(1)
+	}
+
+	if *backend != "file" {
+		for key, value := range meta {
+			writerFunc(key, value)
+		}
+	}
+
+	if err := generateIndex(keyFunc, groupFunc, writerFunc, *inputFileName); err != nil {
+		fmt.Println(err)
 		os.Exit(1)
 	}
-	generateIndex(keyFunc, *inputFileName)
 }
@@ -6,10 +6,12 @@ import (
 	"fmt"
 	parser "github.com/Cgboal/DomainParser"
 	"github.com/cgboal/sonarsearch/pkg/ipconv"
+	"github.com/cgboal/sonarsearch/pkg/search"
 	jsoniter "github.com/json-iterator/go"
 	"log"
 	"os"
 	"runtime"
+	"strings"
 	"sync"
 )
 
@@ -31,11 +33,76 @@ type FormatterFunc func(SonarEntry) (string, error)
 
 func DomainLookupFormatter(entry SonarEntry) (string, error) {
 	domainStruct := dp.ParseDomain(entry.Name)
-	outputLine := fmt.Sprintf("%s,%s,%s\n", domainStruct.Domain, domainStruct.TLD, domainStruct.Subdomain)
+	outputLine := fmt.Sprintf("%s,%s,%s,%s\n", domainStruct.Domain, domainStruct.TLD, domainStruct.Subdomain, entry.Value)
 
 	return outputLine, nil
 }
 
+// ZoneLookupFormatter writes the domain dataset with the labels of each
+// subdomain reversed, so that sorting it keeps the names under a sub-zone
+// together.
+func ZoneLookupFormatter(entry SonarEntry) (string, error) {
+	domainStruct := dp.ParseDomain(entry.Name)
+	outputLine := fmt.Sprintf("%s,%s,%s,%s\n", domainStruct.Domain, domainStruct.TLD, search.ZoneLabels(domainStruct.Subdomain), entry.Value)
+
+	return outputLine, nil
+}
+
+// SuffixLookupFormatter keys the domain dataset by public suffix, so that the
+// registered domains under a suffix can be listed.
+func SuffixLookupFormatter(entry SonarEntry) (string, error) {
+	domainStruct := dp.ParseDomain(entry.Name)
+	outputLine := fmt.Sprintf("%s,%s,%s\n", domainStruct.TLD, domainStruct.Domain, domainStruct.Subdomain)
+
+	return outputLine, nil
+}
+
+// recordFormats lists the record types which can be ingested with
+// RecordLookupFormatter, or ReverseRecordLookupFormatter using the
+// `<type>_reverse` format.
+var recordFormats = []string{"cname", "ns", "mx"}
+
+// recordValue normalises a record value, dropping the preference from MX
+// records and the trailing dot from names.
+func recordValue(entry SonarEntry) string {
+	value := entry.Value
+	if entry.Type == "mx" {
+		if spacePos := strings.LastIndexByte(value, ' '); spacePos != -1 {
+			value = value[spacePos+1:]
+		}
+	}
+
+	return strings.TrimSuffix(value, ".")
+}
+
+// RecordLookupFormatter keys a record by its name, storing the record value
+// in a fourth column after the domain layout.
+func RecordLookupFormatter(entry SonarEntry) (string, error) {
+	domainStruct := dp.ParseDomain(entry.Name)
+	outputLine := fmt.Sprintf("%s,%s,%s,%s\n", domainStruct.Domain, domainStruct.TLD, domainStruct.Subdomain, recordValue(entry))
+
+	return outputLine, nil
+}
+
+// ReverseRecordLookupFormatter keys a record by its value, such as the target
+// of a CNAME, storing the record name in the fourth column.
+func ReverseRecordLookupFormatter(entry SonarEntry) (string, error) {
+	domainStruct := dp.ParseDomain(recordValue(entry))
+	outputLine := fmt.Sprintf("%s,%s,%s,%s\n", domainStruct.Domain, domainStruct.TLD, domainStruct.Subdomain, entry.Name)
+
+	return outputLine, nil
+}
+
+func isRecordFormat(format string) bool {
+	for _, recordFormat := range recordFormats {
+		if format == recordFormat {
+			return true
+		}
+	}
+
+	return false
+}
+
 func ReverseDomainLookupFormatter(entry SonarEntry) (string, error) {
 	ipv4Int, err := ipconv.IPv4ToInt(entry.Value)
 	if err != nil {
@@ -45,7 +112,18 @@ func ReverseDomainLookupFormatter(entry SonarEntry) (string, error) {
 	return outputLine, nil
 }
 
-func Map(formatterFunc FormatterFunc, inputChan <-chan []byte, lines chan string) {
+// ReverseIPv6LookupFormatter stores addresses as 32 hex digits, which sort
+// in address order with `LC_ALL=C sort`.
+func ReverseIPv6LookupFormatter(entry SonarEntry) (string, error) {
+	ipv6Hex, err := ipconv.IPv6ToHex(entry.Value)
+	if err != nil {
+		return "", err
+	}
+	outputLine := fmt.Sprintf("%s,%s\n", ipv6Hex, entry.Name)
+	return outputLine, nil
+}
+
+func Map(formatterFunc FormatterFunc, recordType string, inputChan <-chan []byte, lines chan string) {
 	for line := range inputChan {
 		var entry SonarEntry
 		err := json.Unmarshal(line, &entry)
@@ -55,7 +133,7 @@ func Map(formatterFunc FormatterFunc, inputChan <-chan []byte, lines chan string
 			continue
 		}
 
-		if entry.Type != "a" {
+		if entry.Type != recordType {
 			continue
 		}
 
@@ -87,7 +165,8 @@ func Reducer(outputFileName string, lines <-chan string) {
 func main() {
 	inputFileName := flag.String("i", "", "file path for raw sonar dataset")
 	outputFileName := flag.String("o", "", "file path to store new, sorted dataset")
-	format := flag.String("f", "", "what output format to use, can be 'domain' or 'reverse'")
+	format := flag.String("f", "", "what output format to use, can be 'domain', 'zone', 'suffix', 'reverse', 'reverse6', or one of 'cname', 'ns' and 'mx' optionally suffixed with '_reverse'")
+	recordType := flag.String("t", "", "which record type to ingest, defaults to the type used by the format")
 
 	flag.Parse()
 
@@ -96,15 +175,33 @@ func main() {
 	}
 
 	var formatter FormatterFunc
+	defaultType := "a"
 	if *format == "domain" {
 		formatter = DomainLookupFormatter
+	} else if *format == "zone" {
+		formatter = ZoneLookupFormatter
+	} else if *format == "suffix" {
+		formatter = SuffixLookupFormatter
 	} else if *format == "reverse" {
 		formatter = ReverseDomainLookupFormatter
+	} else if *format == "reverse6" {
+		formatter = ReverseIPv6LookupFormatter
+		defaultType = "aaaa"
+	} else if isRecordFormat(strings.TrimSuffix(*format, "_reverse")) {
+		formatter = RecordLookupFormatter
+		if strings.HasSuffix(*format, "_reverse") {
+			formatter = ReverseRecordLookupFormatter
+		}
+		defaultType = strings.TrimSuffix(*format, "_reverse")
 	} else {
-		fmt.Println("Format must be either 'domain' or 'reverse', got " + *format)
+		fmt.Println("Format must be either 'domain', 'zone', 'suffix', 'reverse', 'reverse6', or one of 'cname', 'ns' and 'mx' optionally suffixed with '_reverse', got " + *format)
 		os.Exit(1)
 	}
 
+	if *recordType == "" {
+		*recordType = defaultType
+	}
+
 	inputFile, err := os.Open(*inputFileName)
 	if err != nil {
 		log.Fatal(err)
@@ -130,7 +227,7 @@ func main() {
 		mapWg.Add(1)
 		go func() {
 			defer mapWg.Done()
-			Map(formatter, inputChan, lines)
+			Map(formatter, *recordType, inputChan, lines)
 		}()
 	}
 
@@ -0,0 +1,42 @@
+// Package grpcstatus gives the errors returned by searches the gRPC status
+// codes which crobat-server sends, so that queries run without a server fail
+// in the same way.
+package grpcstatus
+
+import (
+	"errors"
+	"io"
+
+	"github.com/cgboal/sonarsearch/pkg/search"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FromSearch converts the errors which stop a search into gRPC statuses, so
+// that clients can tell missing results, bad queries and failed backends
+// apart. The end of a search is not an error, and converts to nil.
+func FromSearch(err error) error {
+	switch {
+	case err == nil || err == io.EOF:
+		return nil
+	case errors.Is(err, search.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, search.ErrInvalidQuery), errors.Is(err, search.ErrInvalidCursor):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, search.ErrQueueFull):
+		// the queue is full for now, so the query may be retried later
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, search.ErrUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, search.ErrCanceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, search.ErrDeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, search.ErrBudgetExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, search.ErrStaleCursor):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
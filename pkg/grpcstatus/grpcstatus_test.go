@@ -0,0 +1,40 @@
+package grpcstatus
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/cgboal/sonarsearch/pkg/search"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFromSearch(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{err: search.ErrNotFound, code: codes.NotFound},
+		{err: &search.QueryError{Reason: "bad query"}, code: codes.InvalidArgument},
+		{err: search.ErrInvalidCursor, code: codes.InvalidArgument},
+		{err: &search.ConfigError{What: "domain dataset"}, code: codes.Unavailable},
+		{err: search.ErrCanceled, code: codes.Canceled},
+		{err: search.ErrDeadlineExceeded, code: codes.DeadlineExceeded},
+		{err: search.ErrBudgetExceeded, code: codes.ResourceExhausted},
+		{err: search.ErrQueueFull, code: codes.ResourceExhausted},
+		{err: search.ErrStaleCursor, code: codes.FailedPrecondition},
+		{err: errors.New("unexpected"), code: codes.Internal},
+	}
+
+	for _, test := range tests {
+		if code := status.Code(FromSearch(test.err)); code != test.code {
+			t.Errorf("FromSearch(%v) = %s, want %s", test.err, code, test.code)
+		}
+	}
+
+	// the end of a search is not an error
+	if err := FromSearch(io.EOF); err != nil {
+		t.Errorf("FromSearch(io.EOF) = %v, want nil", err)
+	}
+}
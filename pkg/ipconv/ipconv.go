@@ -2,6 +2,7 @@ package ipconv
 
 import (
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"math"
 	"net"
@@ -62,6 +63,16 @@ func RoundDecIP(IPv4Int uint32, roundTo uint32) uint32 {
 	return uint32(roundedValue)
 }
 
+// PrefixBucket returns the network number of the prefixLen prefix holding
+// IPv4Int.
+func PrefixBucket(IPv4Int uint32, prefixLen uint32) uint32 {
+	if prefixLen == 0 {
+		return 0
+	}
+
+	return IPv4Int >> (32 - prefixLen)
+}
+
 func RoundIP(IPv4String string, roundTo uint32) (uint32, error) {
 	IPv4Int, err := IPv4ToInt(IPv4String)
 	if err != nil {
@@ -82,7 +93,11 @@ func CIDRMinMaxInt(cidr string) (uint32, uint32, error) {
 		return 0, 0, errors.New("Invalid IPv4 address")
 	}
 
-	cidrInt, _ := strconv.ParseInt(cidr, 10, 64)
+	cidrInt, err := strconv.ParseInt(cidr, 10, 64)
+	if err != nil || cidrInt < 0 || cidrInt > 32 {
+		return 0, 0, errors.New("Invalid IPv4 prefix length " + cidr)
+	}
+
 	var minIPv4, maxIPv4 string
 	if cidrInt == 32 {
 		minIPv4 = ipv4
@@ -107,3 +122,43 @@ func CIDRMinMaxInt(cidr string) (uint32, uint32, error) {
 
 	return min, max, nil
 }
+
+// IPv6ToHex returns the 32 digit, zero padded hex form of an IPv6 address,
+// which sorts in the same order as the addresses themselves.
+func IPv6ToHex(IPv6String string) (string, error) {
+	IPv6Addr := net.ParseIP(IPv6String)
+	if IPv6Addr == nil || IPv6Addr.To4() != nil {
+		return "", errors.New("invalid IPv6 address: " + IPv6String)
+	}
+
+	return hex.EncodeToString(IPv6Addr.To16()), nil
+}
+
+func HexToIPv6(IPv6Hex string) (string, error) {
+	ipBytes, err := hex.DecodeString(IPv6Hex)
+	if err != nil || len(ipBytes) != net.IPv6len {
+		return "", errors.New("invalid IPv6 hex: " + IPv6Hex)
+	}
+
+	return net.IP(ipBytes).String(), nil
+}
+
+// CIDRMinMax6 returns the first and last addresses of an IPv6 CIDR range.
+func CIDRMinMax6(cidr string) (net.IP, net.IP, error) {
+	ip, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, nil, errors.New("Invalid IPv6 range")
+	}
+
+	if ip.To4() != nil {
+		return nil, nil, errors.New("Invalid IPv6 address")
+	}
+
+	min := network.IP.To16()
+	max := make(net.IP, net.IPv6len)
+	for i := range min {
+		max[i] = min[i] | ^network.Mask[i]
+	}
+
+	return min, max, nil
+}
@@ -0,0 +1,42 @@
+// Package output formats results as rows of named fields, which both the
+// REST API's streams and the client write as JSON objects, CSV or text.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONObject encodes values as a JSON object, each keyed by the name at the
+// same position in fields. Unlike a map, the object keeps the fields in the
+// order given. Values are strings, string lists and numbers, which always
+// encode.
+func JSONObject(fields []string, values []interface{}) []byte {
+	object := []byte{'{'}
+	for i, value := range values {
+		if i > 0 {
+			object = append(object, ',')
+		}
+		key, _ := json.Marshal(fields[i])
+		encoded, _ := json.Marshal(value)
+		object = append(append(append(object, key...), ':'), encoded...)
+	}
+
+	return append(object, '}')
+}
+
+// Text formats value as a CSV or text field, with lists separated by spaces.
+func Text(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case []string:
+		return strings.Join(value, " ")
+	case int64:
+		return strconv.FormatInt(value, 10)
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,30 @@
+package output
+
+import "testing"
+
+func TestJSONObject(t *testing.T) {
+	object := JSONObject([]string{"query", "domain", "ips", "subdomain_count"}, []interface{}{"example.com", "www.example.com", []string{"1.2.3.4"}, int64(2)})
+
+	// the fields keep their order, rather than being sorted as in a map
+	want := `{"query":"example.com","domain":"www.example.com","ips":["1.2.3.4"],"subdomain_count":2}`
+	if string(object) != want {
+		t.Errorf("JSONObject = %s, want %s", object, want)
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		text  string
+	}{
+		{value: "www.example.com", text: "www.example.com"},
+		{value: []string{"1.2.3.4", "1.2.3.5"}, text: "1.2.3.4 1.2.3.5"},
+		{value: int64(42), text: "42"},
+	}
+
+	for _, test := range tests {
+		if text := Text(test.value); text != test.text {
+			t.Errorf("Text(%v) = %q, want %q", test.value, text, test.text)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package search
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+const maxLineLength = 10240
+
+// readLine returns the line beginning at off, without its trailing newline.
+func readLine(r io.ReaderAt, off int64) ([]byte, error) {
+	buf := make([]byte, 256)
+	line := []byte{}
+	for len(line) < maxLineLength {
+		n, err := r.ReadAt(buf, off+int64(len(line)))
+		if i := bytes.IndexByte(buf[:n], '\n'); i != -1 {
+			return append(line, buf[:i]...), nil
+		}
+		line = append(line, buf[:n]...)
+		if err == io.EOF {
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, errors.New("line exceeds maximum length")
+}
+
+// nextLineStart realigns off to the beginning of the first line starting at
+// or after it.
+func nextLineStart(r io.ReaderAt, off int64) (int64, error) {
+	if off == 0 {
+		return 0, nil
+	}
+
+	partial, err := readLine(r, off-1)
+	if err != nil {
+		return 0, err
+	}
+
+	return off + int64(len(partial)), nil
+}
+
+// bisect returns the offset of the first line in [lo, hi) for which cmp
+// returns a value >= 0, or hi if there is no such line. lo must be the start
+// of a line and the lines must be sorted with respect to cmp.
+func bisect(r io.ReaderAt, lo int64, hi int64, cmp func(line []byte) int) (int64, error) {
+	for lo < hi {
+		mid, err := nextLineStart(r, lo+(hi-lo)/2)
+		if err != nil {
+			return 0, err
+		}
+
+		if mid >= hi {
+			break
+		}
+
+		line, err := readLine(r, mid)
+		if err != nil {
+			return 0, err
+		}
+
+		if cmp(line) >= 0 {
+			hi = mid
+		} else {
+			lo = mid + int64(len(line)) + 1
+		}
+	}
+
+	// the remaining lines between lo and hi could not be split, so walk them
+	for lo < hi {
+		line, err := readLine(r, lo)
+		if err != nil {
+			return 0, err
+		}
+
+		if cmp(line) >= 0 {
+			return lo, nil
+		}
+		lo = lo + int64(len(line)) + 1
+	}
+
+	return hi, nil
+}
+
+// compareNumeric orders two unsigned decimal strings by value.
+func compareNumeric(a []byte, b []byte) int {
+	a = bytes.TrimLeft(a, "0")
+	b = bytes.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+
+	return bytes.Compare(a, b)
+}
+
+func firstField(line []byte) []byte {
+	delimPos := bytes.IndexByte(line, ',')
+	if delimPos == -1 {
+		return line
+	}
+
+	return line[:delimPos]
+}
@@ -0,0 +1,205 @@
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+)
+
+type lineKeyFunc func(line []byte) []byte
+
+type indexSample struct {
+	key    []byte
+	offset int64
+}
+
+// BisectIndex finds offsets by binary searching a sorted dataset file
+// directly, so no index needs to be built with crobat2index. Optionally, the
+// keys at evenly spaced offsets are sampled when the index is opened to
+// narrow down each search.
+type BisectIndex struct {
+	file    *os.File
+	size    int64
+	lineKey lineKeyFunc
+	compare func(a []byte, b []byte) int
+	samples []indexSample
+	meta    map[string]string
+}
+
+// NewDatasetBisectIndex opens the sorted file for dataset. prefixLen sets the
+// bucket size of the reverse datasets, and is ignored for the others.
+func NewDatasetBisectIndex(dataset string, fileName string, samples int, prefixLen uint32) (*BisectIndex, error) {
+	switch dataset {
+	case ReverseDataset:
+		return NewReverseBisectIndex(fileName, samples, prefixLen)
+	case Reverse6Dataset:
+		return NewReverse6BisectIndex(fileName, samples, prefixLen)
+	default:
+		return NewRecordBisectIndex(dataset, fileName, samples)
+	}
+}
+
+// NewRecordBisectIndex opens a domain or other record dataset sorted with
+// LC_ALL=C.
+func NewRecordBisectIndex(dataset string, fileName string, samples int) (*BisectIndex, error) {
+	lineKey := func(line []byte) []byte {
+		return []byte(datasetKey(dataset, string(firstField(line))))
+	}
+
+	return openBisectIndex(fileName, samples, lineKey, bytes.Compare)
+}
+
+// NewReverseBisectIndex opens a reverse dataset, bucketing IPs by prefixLen
+// in the same way as an index built with `crobat2index -bucket`. A prefixLen
+// of 32 makes range queries start exactly at the first IP in the range.
+func NewReverseBisectIndex(fileName string, samples int, prefixLen uint32) (*BisectIndex, error) {
+	lineKey := func(line []byte) []byte {
+		ipv4, _ := strconv.ParseUint(string(firstField(line)), 10, 32)
+		return []byte(fmt.Sprint(reverseBucket(uint32(ipv4), prefixLen)))
+	}
+
+	bi, err := openBisectIndex(fileName, samples, lineKey, compareNumeric)
+	if err != nil {
+		return nil, err
+	}
+
+	if prefixLen != 0 {
+		bi.meta[ReverseBucketKey] = fmt.Sprint(prefixLen)
+	}
+
+	return bi, nil
+}
+
+func NewReverse6BisectIndex(fileName string, samples int, prefixLen uint32) (*BisectIndex, error) {
+	lineKey := func(line []byte) []byte {
+		field := firstField(line)
+		if len(field) < int(prefixLen/4) {
+			return field
+		}
+		return reverse6Key(field, prefixLen)
+	}
+
+	bi, err := openBisectIndex(fileName, samples, lineKey, bytes.Compare)
+	if err != nil {
+		return nil, err
+	}
+
+	bi.meta[Reverse6BucketKey] = fmt.Sprint(prefixLen)
+	return bi, nil
+}
+
+func openBisectIndex(fileName string, samples int, lineKey lineKeyFunc, compare func(a []byte, b []byte) int) (*BisectIndex, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	bi := BisectIndex{
+		file:    file,
+		size:    info.Size(),
+		lineKey: lineKey,
+		compare: compare,
+		meta:    map[string]string{},
+	}
+
+	for i := 0; i < samples; i++ {
+		offset, err := nextLineStart(file, bi.size*int64(i)/int64(samples))
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		if offset >= bi.size || (len(bi.samples) > 0 && bi.samples[len(bi.samples)-1].offset == offset) {
+			continue
+		}
+
+		line, err := readLine(file, offset)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		bi.samples = append(bi.samples, indexSample{key: lineKey(line), offset: offset})
+	}
+
+	return &bi, nil
+}
+
+// Meta returns the metadata which the index was opened with, such as the
+// bucket size of a reverse dataset.
+func (bi *BisectIndex) Meta(key string) (string, error) {
+	value, exists := bi.meta[key]
+	if !exists {
+		return "", errKeyNotFound
+	}
+
+	return value, nil
+}
+
+func (bi *BisectIndex) Get(key string) (string, error) {
+	lineKey, value, err := bi.Ceil(key)
+	if err != nil {
+		return "", err
+	}
+
+	if bi.compare([]byte(lineKey), []byte(key)) != 0 {
+		return "", errKeyNotFound
+	}
+
+	return value, nil
+}
+
+// Ceil returns the first key at or after key, along with its offset.
+func (bi *BisectIndex) Ceil(key string) (string, string, error) {
+	needle := []byte(key)
+	lo, hi := bi.sampleRange(needle)
+
+	pos, err := bisect(bi.file, lo, hi, func(line []byte) int {
+		return bi.compare(bi.lineKey(line), needle)
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	if pos >= bi.size {
+		return "", "", errKeyNotFound
+	}
+
+	line, err := readLine(bi.file, pos)
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(bi.lineKey(line)), fmt.Sprint(pos), nil
+}
+
+// sampleRange returns the smallest sampled range of the file which can hold
+// the first line for needle.
+func (bi *BisectIndex) sampleRange(needle []byte) (int64, int64) {
+	i := sort.Search(len(bi.samples), func(i int) bool {
+		return bi.compare(bi.samples[i].key, needle) >= 0
+	})
+
+	lo, hi := int64(0), bi.size
+	if i > 0 {
+		lo = bi.samples[i-1].offset
+	}
+	if i < len(bi.samples) {
+		// bisect returns hi when nothing before it matches, which is
+		// then the sampled line itself
+		hi = bi.samples[i].offset
+	}
+
+	return lo, hi
+}
+
+func (bi *BisectIndex) Close() error {
+	return bi.file.Close()
+}
@@ -0,0 +1,67 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// maxCNAMEChain bounds how many CNAME records are followed, in case the
+// dataset contains a loop which is not caught by the seen check.
+const maxCNAMEChain = 32
+
+type CNAMERecord struct {
+	Name   string
+	Target string
+}
+
+// CNAMEChain follows the CNAME records for name through the cname dataset,
+// returning each hop in order.
+func CNAMEChain(ctx context.Context, dataset Dataset, name string) ([]CNAMERecord, error) {
+	chain := []CNAMERecord{}
+	seen := map[string]struct{}{name: {}}
+
+	for len(chain) < maxCNAMEChain {
+		searcher, err := NewRecordSearch(ctx, dataset, name, ExactDomainNeedle, nil)
+		// the chain ends at a name without records, but a failed lookup
+		// would cut it short, so is returned
+		if errors.Is(err, ErrNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		found := searcher.Next()
+		target := searcher.Value()
+		err = searcher.Error()
+		searcher.Close()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if !found {
+			break
+		}
+
+		chain = append(chain, CNAMERecord{Name: name, Target: target})
+		if _, exists := seen[target]; exists {
+			break
+		}
+		seen[target] = struct{}{}
+		name = target
+	}
+
+	if len(chain) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return chain, nil
+}
+
+// NewCNAMEAliasSearch finds the names which are aliases of target, using the
+// cname_reverse dataset. If target is a registered domain, such as
+// azurewebsites.net, aliases of any name beneath it are returned too.
+// Text returns the target of each alias, and Value the alias itself.
+func NewCNAMEAliasSearch(ctx context.Context, dataset Dataset, target string, cursor *Cursor) (*DomainSearch, error) {
+	return NewRecordNameSearch(ctx, dataset, target, cursor)
+}
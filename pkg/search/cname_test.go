@@ -0,0 +1,64 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// failingIndex fails lookups of one key, as a backend which has gone away
+// would, and passes the rest to Index.
+type failingIndex struct {
+	Index
+	key string
+}
+
+var errBackend = errors.New("connection refused")
+
+func (fi *failingIndex) Get(key string) (string, error) {
+	if key == fi.key {
+		return "", errBackend
+	}
+
+	return fi.Index.Get(key)
+}
+
+func TestCNAMEChain(t *testing.T) {
+	dataset := writeDataset(t, CNAMEDataset,
+		"cdn,net,edge,edge.cdnhost.org",
+		"cdn,net,www,edge.cdn.net",
+		"example,com,www,www.cdn.net",
+	)
+
+	chain, err := CNAMEChain(context.Background(), dataset, "www.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CNAMERecord{
+		{Name: "www.example.com", Target: "www.cdn.net"},
+		{Name: "www.cdn.net", Target: "edge.cdn.net"},
+		{Name: "edge.cdn.net", Target: "edge.cdnhost.org"},
+	}
+	if !reflect.DeepEqual(chain, want) {
+		t.Errorf("CNAMEChain = %v, want %v", chain, want)
+	}
+
+	if _, err := CNAMEChain(context.Background(), dataset, "mail.example.com"); err != ErrNotFound {
+		t.Errorf("CNAMEChain(mail.example.com) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCNAMEChainBackendError(t *testing.T) {
+	dataset := writeDataset(t, CNAMEDataset,
+		"cdn,net,edge,edge.cdnhost.org",
+		"example,com,www,edge.cdn.net",
+	)
+	// the second hop cannot be looked up
+	failing := NewFileDataset(CNAMEDataset, dataset.fileName, &failingIndex{Index: dataset.Index(), key: datasetKey(CNAMEDataset, "cdn")})
+
+	chain, err := CNAMEChain(context.Background(), failing, "www.example.com")
+	if !errors.Is(err, errBackend) || !errors.Is(err, ErrUnavailable) {
+		t.Errorf("CNAMEChain = %v, %v, want the backend error", chain, err)
+	}
+}
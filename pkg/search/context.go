@@ -0,0 +1,160 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrCanceled is returned once the caller of a query has gone away, such as
+// a gRPC client disconnecting.
+var ErrCanceled = errors.New("query canceled")
+
+// ErrDeadlineExceeded is returned when a query runs past its deadline.
+var ErrDeadlineExceeded = errors.New("query deadline exceeded")
+
+// ErrBudgetExceeded is returned when a query reads more of a dataset, or
+// steps through more results, than its budget allows.
+var ErrBudgetExceeded = errors.New("query budget exceeded")
+
+// IOError is returned when a dataset could not be read.
+type IOError struct {
+	Err error
+}
+
+func (e *IOError) Error() string {
+	return "reading dataset: " + e.Err.Error()
+}
+
+func (e *IOError) Unwrap() error {
+	return e.Err
+}
+
+// IndexError is returned when the index of a dataset could not be read, such
+// as when Redis is unreachable.
+type IndexError struct {
+	Err error
+}
+
+func (e *IndexError) Error() string {
+	return "reading index: " + e.Err.Error()
+}
+
+func (e *IndexError) Unwrap() error {
+	return e.Err
+}
+
+// Budget bounds the work done by a single query. Zero values are unlimited.
+type Budget struct {
+	// MaxBytes bounds how much of the dataset is read
+	MaxBytes int64
+	// MaxRecords bounds how many results are stepped through, including
+	// those which are skipped
+	MaxRecords int
+}
+
+type budgetKey struct{}
+
+// WithBudget returns a copy of ctx which limits the queries run with it to
+// budget.
+func WithBudget(ctx context.Context, budget Budget) context.Context {
+	return context.WithValue(ctx, budgetKey{}, budget)
+}
+
+func budgetFrom(ctx context.Context) Budget {
+	budget, _ := ctx.Value(budgetKey{}).(Budget)
+	return budget
+}
+
+// QueryLimits holds the deadline and budget applied to each query.
+type QueryLimits struct {
+	Timeout time.Duration
+	Budget  Budget
+}
+
+// Context derives the context for a single query from parent, which is
+// usually that of the request being served.
+func (ql QueryLimits) Context(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx := WithBudget(parent, ql.Budget)
+	if ql.Timeout > 0 {
+		return context.WithTimeout(ctx, ql.Timeout)
+	}
+
+	return context.WithCancel(ctx)
+}
+
+func contextError(err error) error {
+	switch err {
+	case context.Canceled:
+		return ErrCanceled
+	case context.DeadlineExceeded:
+		return ErrDeadlineExceeded
+	default:
+		return err
+	}
+}
+
+// lineScanner reads a dataset line by line, stopping as soon as the query is
+// canceled or has used up its budget. Err returns io.EOF once the end of the
+// dataset is reached.
+type lineScanner struct {
+	ctx       context.Context
+	scanner   *bufio.Scanner
+	budget    Budget
+	bytesRead int64
+	records   int
+	err       error
+}
+
+func newLineScanner(ctx context.Context, scanner *bufio.Scanner) *lineScanner {
+	return &lineScanner{
+		ctx:     ctx,
+		scanner: scanner,
+		budget:  budgetFrom(ctx),
+	}
+}
+
+func (ls *lineScanner) Scan() bool {
+	if err := ls.ctx.Err(); err != nil {
+		ls.err = contextError(err)
+		return false
+	}
+
+	if !ls.scanner.Scan() {
+		ls.err = io.EOF
+		if err := ls.scanner.Err(); err != nil {
+			ls.err = &IOError{Err: err}
+		}
+		return false
+	}
+
+	ls.bytesRead += int64(len(ls.scanner.Bytes())) + 1
+	if ls.budget.MaxBytes > 0 && ls.bytesRead > ls.budget.MaxBytes {
+		ls.err = ErrBudgetExceeded
+		return false
+	}
+
+	return true
+}
+
+func (ls *lineScanner) Bytes() []byte {
+	return ls.scanner.Bytes()
+}
+
+// record counts a result against the budget, and reports whether it may be
+// returned.
+func (ls *lineScanner) record() bool {
+	ls.records++
+	if ls.budget.MaxRecords > 0 && ls.records > ls.budget.MaxRecords {
+		ls.err = ErrBudgetExceeded
+		return false
+	}
+
+	return true
+}
+
+func (ls *lineScanner) Err() error {
+	return ls.err
+}
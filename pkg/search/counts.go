@@ -0,0 +1,235 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"strings"
+
+	parser "github.com/Cgboal/DomainParser"
+)
+
+// Count steps through the remaining results of s and returns how many there
+// were, without holding onto them.
+func Count(s Searcher) (int64, error) {
+	var count int64
+	for s.Next() {
+		count++
+	}
+
+	if err := s.Error(); err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// countSearch counts the results of a search which may not have been opened,
+// treating a query with no results as a count of zero.
+func countSearch(s Searcher, err error) (int64, error) {
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer s.Close()
+
+	return Count(s)
+}
+
+// CountRecords counts the results NewRecordSearch returns for query. Queries
+// for every name with a label, such as those made with DomainNeedle, are
+// answered from the index when it records counts, as are those for every
+// name under a registered domain when no other shares its index key. Others
+// are counted by scanning the dataset.
+func CountRecords(ctx context.Context, dataset Dataset, query string, needleFunc domainNeedleFunc) (int64, error) {
+	if query == "" {
+		return 0, errBlankQuery
+	}
+
+	queryDomain := dp.ParseDomain(query)
+	needle, err := needleFunc(queryDomain)
+	if err != nil {
+		return 0, err
+	}
+
+	if needle == queryDomain.Domain+"," {
+		stats, err := getStats(dataset.Index(), datasetKey(dataset.Name(), queryDomain.Domain))
+		if err == nil {
+			return stats.count, nil
+		}
+		if err != errNotCounted {
+			return 0, err
+		}
+	}
+	if needle == queryDomain.Domain+","+queryDomain.TLD+"," {
+		count, err := countRegistered(dataset, queryDomain)
+		if err != errNotCounted {
+			return count, err
+		}
+	}
+
+	return countSearch(NewRecordSearch(ctx, dataset, query, needleFunc, nil))
+}
+
+// CountSubzone counts the results NewSubzoneSearch returns for query.
+// Registered domains are answered from the index when it records counts and
+// no other registered domain shares their index key, and sub-zones are
+// counted by scanning the dataset.
+func CountSubzone(ctx context.Context, domains Dataset, zones Dataset, query string) (int64, error) {
+	if query == "" {
+		return 0, errBlankQuery
+	}
+
+	if dp.ParseDomain(query).Subdomain == "" {
+		return CountRecords(ctx, domains, query, FullDomainNeedle)
+	}
+
+	return countSearch(NewSubzoneSearch(ctx, domains, zones, query, nil))
+}
+
+// countRegistered counts the names under a registered domain, such as
+// example.com, from the index. Each index key holds every registered domain
+// with the same label, such as example.net too, so the count is only used
+// once a binary search of the key's lines finds none belonging to another.
+// It returns errNotCounted when the index cannot answer.
+func countRegistered(dataset Dataset, queryDomain parser.Domain) (int64, error) {
+	stats, err := getStats(dataset.Index(), datasetKey(dataset.Name(), queryDomain.Domain))
+	if err != nil {
+		return 0, err
+	}
+	if stats.start == stats.end {
+		return 0, nil
+	}
+
+	randomDataset, ok := dataset.(RandomAccessDataset)
+	if !ok {
+		return 0, errNotCounted
+	}
+
+	reader, _, err := randomDataset.OpenReaderAt()
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	// the lines of the domain lie between the first at or after
+	// `domain,tld,` and the first at or after `domain,tld-`, as '-' sorts
+	// straight after ','
+	prefix := []byte(queryDomain.Domain + "," + queryDomain.TLD + ",")
+	after := append(prefix[:len(prefix)-1:len(prefix)-1], '-')
+	first, err := bisect(reader, stats.start, stats.end, func(line []byte) int {
+		return bytes.Compare(line, prefix)
+	})
+	if err != nil {
+		return 0, &IOError{Err: err}
+	}
+	last, err := bisect(reader, stats.start, stats.end, func(line []byte) int {
+		return bytes.Compare(line, after)
+	})
+	if err != nil {
+		return 0, &IOError{Err: err}
+	}
+
+	if first != stats.start || last != stats.end {
+		return 0, errNotCounted
+	}
+
+	return stats.count, nil
+}
+
+// CountTLDs counts the registered domains NewTLDSearch returns for query.
+// Each index key mixes the domains of every TLD, so they are counted by
+// scanning the lines of the key.
+func CountTLDs(ctx context.Context, dataset Dataset, query string) (int64, error) {
+	if query == "" {
+		return 0, errBlankQuery
+	}
+
+	return countSearch(NewTLDSearch(ctx, dataset, query, nil))
+}
+
+// CountSuffixDomains counts the registered domains under suffix, using the
+// index when it records counts.
+func CountSuffixDomains(ctx context.Context, dataset Dataset, suffix string) (int64, error) {
+	suffix = strings.Trim(suffix, ".")
+	if suffix == "" {
+		return 0, errBlankQuery
+	}
+
+	stats, err := getStats(dataset.Index(), datasetKey(dataset.Name(), suffix))
+	if err == nil {
+		return stats.count, nil
+	}
+	if err != errNotCounted {
+		return 0, err
+	}
+
+	return countSearch(NewSuffixSearch(ctx, dataset, suffix, nil))
+}
+
+// CountReverse counts the names found by a reverse lookup of an address or
+// range. Buckets lying wholly inside the range are counted from the index,
+// so only the buckets at either end of the range are scanned.
+func CountReverse(ctx context.Context, dataset Dataset, query string) (int64, error) {
+	if query == "" {
+		return 0, errBlankQuery
+	}
+
+	needle, err := newReverseNeedle(query)
+	if err != nil {
+		return 0, err
+	}
+
+	idx := dataset.Index()
+	if idx == nil {
+		return 0, &ConfigError{What: "index for this address family"}
+	}
+
+	buckets, err := newReverseRange(idx, needle)
+	if err != nil {
+		return 0, err
+	}
+
+	min, max := new(big.Int).SetBytes(needle.Min), new(big.Int).SetBytes(needle.Max)
+	if !needle.IPv6 {
+		min, max = new(big.Int).SetBytes(needle.Min.To4()), new(big.Int).SetBytes(needle.Max.To4())
+	}
+
+	var count int64
+	err = walkBuckets(idx, buckets, func(bucket *big.Int, val string) error {
+		stats := parseKeyStats(val)
+		if !stats.counted {
+			return errNotCounted
+		}
+
+		lo, hi := buckets.span(bucket)
+		if lo.Cmp(min) >= 0 && hi.Cmp(max) <= 0 {
+			count += stats.count
+			return nil
+		}
+
+		searcher, err := newReverseSearch(ctx, dataset, needle, stats.start, nil)
+		if err != nil {
+			return err
+		}
+		searcher.limit = stats.end
+		defer searcher.Close()
+
+		edge, err := Count(searcher)
+		count += edge
+		return err
+	})
+
+	if err == errNotCounted {
+		return countSearch(NewReverseSearch(ctx, dataset, query, nil))
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
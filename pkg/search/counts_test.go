@@ -0,0 +1,125 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCountedDataset writes a domain dataset of the given lines, which must
+// already be sorted, along with an index recording the counts given for each
+// key, as crobat2index would.
+func writeCountedDataset(t *testing.T, counts map[string]int, lines ...string) *FileDataset {
+	t.Helper()
+
+	fileName := filepath.Join(t.TempDir(), DomainDataset)
+	if err := os.WriteFile(fileName, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var index strings.Builder
+	index.WriteString("#order,lexical\n")
+	var pos, start int64
+	for i, line := range lines {
+		key := line[:strings.IndexByte(line, ',')]
+		pos += int64(len(line)) + 1
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], key+",") {
+			continue
+		}
+
+		fmt.Fprintf(&index, "%s,%d:%d:%d\n", key, start, pos, counts[key])
+		start = pos
+	}
+
+	return NewFileDataset(DomainDataset, fileName, writeFileIndex(t, index.String()))
+}
+
+func TestCountSubzone(t *testing.T) {
+	// the counts differ from the number of lines, so that counts answered
+	// from the index can be told apart from those found by scanning
+	dataset := writeCountedDataset(t, map[string]int{"example": 10, "other": 20},
+		"example,co.uk,www,1.2.3.4",
+		"example,com,a,1.2.3.5",
+		"example,com,b,1.2.3.6",
+		"example,org,www,1.2.3.7",
+		"other,com,a,1.2.3.8",
+		"other,com,b,1.2.3.9",
+		"other,com,b,1.2.3.10",
+	)
+
+	tests := []struct {
+		query string
+		count int64
+	}{
+		// other.com is the only domain under its key
+		{query: "other.com", count: 20},
+		// example.com shares its key with other domains, so is scanned
+		{query: "example.com", count: 2},
+		{query: "example.co.uk", count: 1},
+		// sub-zones are scanned
+		{query: "b.other.com", count: 1},
+		{query: "missing.com", count: 0},
+	}
+
+	for _, test := range tests {
+		count, err := CountSubzone(context.Background(), dataset, nil, test.query)
+		if err != nil || count != test.count {
+			t.Errorf("CountSubzone(%s) = %d, %v, want %d", test.query, count, err, test.count)
+		}
+	}
+}
+
+func TestCountRecords(t *testing.T) {
+	dataset := writeCountedDataset(t, map[string]int{"example": 10, "other": 20},
+		"example,com,a,1.2.3.5",
+		"example,org,www,1.2.3.7",
+		"other,com,a,1.2.3.8",
+	)
+
+	tests := []struct {
+		query      string
+		needleFunc domainNeedleFunc
+		count      int64
+	}{
+		{query: "example.com", needleFunc: DomainNeedle, count: 10},
+		{query: "example.com", needleFunc: TargetNeedle, count: 1},
+		{query: "other.com", needleFunc: TargetNeedle, count: 20},
+		{query: "a.other.com", needleFunc: TargetNeedle, count: 1},
+	}
+
+	for _, test := range tests {
+		count, err := CountRecords(context.Background(), dataset, test.query, test.needleFunc)
+		if err != nil || count != test.count {
+			t.Errorf("CountRecords(%s) = %d, %v, want %d", test.query, count, err, test.count)
+		}
+	}
+}
+
+func TestCountTLDs(t *testing.T) {
+	dataset := writeCountedDataset(t, map[string]int{"example": 10},
+		"example,co.uk,www,1.2.3.4",
+		"example,com,a,1.2.3.5",
+		"example,com,b,1.2.3.6",
+		"example,org,www,1.2.3.7",
+	)
+
+	tests := []struct {
+		query string
+		count int64
+	}{
+		// each registered domain is counted once, however many names it has
+		{query: "example.com", count: 3},
+		{query: "www.example.org", count: 3},
+		{query: "missing.com", count: 0},
+	}
+
+	for _, test := range tests {
+		count, err := CountTLDs(context.Background(), dataset, test.query)
+		if err != nil || count != test.count {
+			t.Errorf("CountTLDs(%s) = %d, %v, want %d", test.query, count, err, test.count)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package search
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded, or was issued
+// by a different query.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrStaleCursor is returned when a cursor was issued before the dataset was
+// replaced, so its offset no longer points at the same lines.
+var ErrStaleCursor = errors.New("cursor has expired, as the dataset has changed")
+
+// Cursor records where a search left off, so that it can be resumed from the
+// same offset rather than by skipping every earlier result.
+type Cursor struct {
+	Dataset string `json:"d"`
+	Version string `json:"v"`
+	// Needle identifies the query, so that a cursor is only accepted by the
+	// search which issued it
+	Needle string `json:"n"`
+	Offset int64  `json:"o"`
+}
+
+// ParseCursor decodes a cursor returned by String.
+func ParseCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Offset < 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// String encodes the cursor as an opaque token, which is safe to use in URLs.
+func (c *Cursor) String() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// check reports whether the cursor was issued by a search for needle against
+// the current version of dataset.
+func (c *Cursor) check(dataset string, version string, needle string) error {
+	if c.Dataset != dataset || c.Needle != needle {
+		return ErrInvalidCursor
+	}
+
+	if c.Version != version {
+		return ErrStaleCursor
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package search
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dataset is a sorted dataset, along with the index recording where each of
+// its keys begins.
+type Dataset interface {
+	// Name identifies the layout of the dataset, such as DomainDataset, and
+	// namespaces its keys in shared index backends
+	Name() string
+	Index() Index
+	// Version changes whenever the dataset is replaced, so that cursors into
+	// an older version are rejected
+	Version() (string, error)
+	// Open returns a reader positioned at offset
+	Open(offset int64) (io.ReadCloser, error)
+}
+
+// ReaderAtCloser is implemented by datasets opened for random access.
+type ReaderAtCloser interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// RandomAccessDataset is implemented by datasets which can be binary
+// searched, which the zone dataset requires.
+type RandomAccessDataset interface {
+	Dataset
+	// OpenReaderAt returns the dataset along with its size
+	OpenReaderAt() (ReaderAtCloser, int64, error)
+}
+
+// FileDataset is a dataset stored in a local file.
+type FileDataset struct {
+	name     string
+	fileName string
+	index    Index
+}
+
+func NewFileDataset(name string, fileName string, index Index) *FileDataset {
+	return &FileDataset{name: name, fileName: fileName, index: index}
+}
+
+func (fd *FileDataset) Name() string {
+	return fd.name
+}
+
+func (fd *FileDataset) Index() Index {
+	return fd.index
+}
+
+// Version identifies the file by its size and modification time.
+func (fd *FileDataset) Version() (string, error) {
+	info, err := os.Stat(fd.fileName)
+	if err != nil {
+		return "", &IOError{Err: err}
+	}
+
+	return fmt.Sprintf("%x-%x", info.Size(), info.ModTime().UnixNano()), nil
+}
+
+func (fd *FileDataset) Open(offset int64) (io.ReadCloser, error) {
+	file, err := os.Open(fd.fileName)
+	if err != nil {
+		return nil, &IOError{Err: err}
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, &IOError{Err: err}
+	}
+
+	return file, nil
+}
+
+func (fd *FileDataset) OpenReaderAt() (ReaderAtCloser, int64, error) {
+	file, err := os.Open(fd.fileName)
+	if err != nil {
+		return nil, 0, &IOError{Err: err}
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, &IOError{Err: err}
+	}
+
+	return file, info.Size(), nil
+}
+
+// Catalog holds the datasets which queries can be run against, keyed by
+// name.
+type Catalog struct {
+	datasets map[string]Dataset
+}
+
+func NewCatalog(datasets ...Dataset) *Catalog {
+	catalog := Catalog{datasets: map[string]Dataset{}}
+	for _, dataset := range datasets {
+		catalog.Add(dataset)
+	}
+
+	return &catalog
+}
+
+// Add makes a dataset available, replacing any other with the same name.
+func (c *Catalog) Add(dataset Dataset) {
+	c.datasets[dataset.Name()] = dataset
+}
+
+func (c *Catalog) Dataset(name string) (Dataset, error) {
+	dataset, exists := c.datasets[name]
+	if !exists {
+		return nil, &ConfigError{What: name + " dataset"}
+	}
+
+	return dataset, nil
+}
+
+// Has reports whether a dataset has been configured, for datasets which are
+// optional.
+func (c *Catalog) Has(name string) bool {
+	_, exists := c.datasets[name]
+	return exists
+}
+
+// ReverseDataset returns the reverse dataset holding the address family of
+// query.
+func (c *Catalog) ReverseDataset(query string) (Dataset, error) {
+	if strings.Contains(query, ":") {
+		return c.Dataset(Reverse6Dataset)
+	}
+
+	return c.Dataset(ReverseDataset)
+}
+
+// Close closes the index of every dataset.
+func (c *Catalog) Close() error {
+	closed := map[Index]struct{}{}
+	var firstErr error
+	for _, dataset := range c.datasets {
+		idx := dataset.Index()
+		if idx == nil {
+			continue
+		}
+
+		// backends such as redis share one index between datasets
+		if _, exists := closed[idx]; exists {
+			continue
+		}
+		closed[idx] = struct{}{}
+
+		if err := idx.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// OpenDirectory returns a catalog of the datasets in dir, each stored in a
+// file named after it, such as dir/domain. Datasets are looked up with the
+// file index beside them, such as dir/domain.idx, when there is one, and are
+// otherwise binary searched, which requires them to be sorted with LC_ALL=C.
+func OpenDirectory(dir string) (*Catalog, error) {
+	catalog := NewCatalog()
+	for _, dataset := range Datasets {
+		fileName := filepath.Join(dir, dataset)
+		if _, err := os.Stat(fileName); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			catalog.Close()
+			return nil, &IOError{Err: err}
+		}
+
+		var idx Index
+		var err error
+		if _, statErr := os.Stat(fileName + ".idx"); statErr == nil {
+			idx, err = OpenFileIndex(fileName + ".idx")
+		} else if dataset == Reverse6Dataset {
+			idx, err = NewDatasetBisectIndex(dataset, fileName, 0, 128)
+		} else {
+			idx, err = NewDatasetBisectIndex(dataset, fileName, 0, 32)
+		}
+		if err != nil {
+			catalog.Close()
+			return nil, err
+		}
+
+		catalog.Add(NewFileDataset(dataset, fileName, idx))
+	}
+
+	if len(catalog.datasets) == 0 {
+		return nil, fmt.Errorf("no datasets found in %s", dir)
+	}
+
+	return catalog, nil
+}
@@ -1,17 +1,13 @@
 package search
 
 import (
-	"bufio"
-	"errors"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
-	"os"
 	"strings"
 
-	"time"
-
 	parser "github.com/Cgboal/DomainParser"
-	"github.com/spf13/viper"
 )
 
 var dp parser.Parser
@@ -23,59 +19,35 @@ func init() {
 type domainNeedleFunc func(parser.Domain) (string, error)
 
 type DomainSearch struct {
-	file       *os.File
-	needle     string
-	needleLen  int
-	scanner    *bufio.Scanner
+	*datasetSearch
+	key        []byte
+	needle     []byte
 	subdomain  string
-	query      []byte
-	err        error
+	value      string
+	values     []string
+	group      bool
+	scope      []byte
+	lineFunc   func(line []byte) (string, string)
 	foundFirst bool
 }
 
-type DomainResponse struct {
-	Subdomains []string
-	Err        error
-}
-
-type DomainQuery struct {
-	Query           string
-	NeedleFunc      domainNeedleFunc
-	Take            int
-	Skip            int
-	ResponseChannel chan DomainResponse
-}
-
-func NewDomainPool(requests <-chan DomainQuery) {
-	for i := 0; i < 5; i++ {
-		go startDomainWorker(requests)
-	}
+// NewDomainSearch searches the domain dataset. cursor resumes an earlier
+// search, and may be nil.
+func NewDomainSearch(ctx context.Context, dataset Dataset, query string, needleFunc domainNeedleFunc, cursor *Cursor) (*DomainSearch, error) {
+	return NewRecordSearch(ctx, dataset, query, needleFunc, cursor)
 }
 
-func startDomainWorker(requests <-chan DomainQuery) {
-	for query := range requests {
-		searcher, err := NewDomainSearch(viper.GetString("domain_file"), query.Query, query.NeedleFunc)
-		if err != nil {
-			query.ResponseChannel <- DomainResponse{
-				Err: err,
-			}
-			continue
-		}
-
-		subdomains := searcher.Skip(query.Skip).Take(query.Take)
-
-		query.ResponseChannel <- DomainResponse{
-			Subdomains: subdomains,
-			Err:        nil,
-		}
-		searcher.Close()
-	}
-
+// NewRecordSearch searches a dataset whose lines are laid out like the domain
+// dataset, as `domain,tld,subdomain[,value]`.
+func NewRecordSearch(ctx context.Context, dataset Dataset, query string, needleFunc domainNeedleFunc, cursor *Cursor) (*DomainSearch, error) {
+	return newRecordSearch(ctx, dataset, query, needleFunc, "", cursor)
 }
 
-func NewDomainSearch(inputFileName string, query string, needleFunc domainNeedleFunc) (*DomainSearch, error) {
+// newRecordSearch is NewRecordSearch, returning only the names which lie
+// under scope when it is set.
+func newRecordSearch(ctx context.Context, dataset Dataset, query string, needleFunc domainNeedleFunc, scope string, cursor *Cursor) (*DomainSearch, error) {
 	if query == "" {
-		return nil, errors.New("query cannot be blank")
+		return nil, errBlankQuery
 	}
 
 	queryDomain := dp.ParseDomain(query)
@@ -85,118 +57,158 @@ func NewDomainSearch(inputFileName string, query string, needleFunc domainNeedle
 		return nil, err
 	}
 
-	pos, err := getPos(queryDomain.Domain)
+	var pos int64
+	if cursor == nil {
+		pos, err = getPos(dataset.Index(), datasetKey(dataset.Name(), queryDomain.Domain))
 
-	if err != nil {
-		return nil, err
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if pos == 0 {
-		return nil, errors.New("no results found")
+	domainSearch := DomainSearch{
+		key:        []byte(queryDomain.Domain + ","),
+		needle:     []byte(needle),
+		group:      dataset.Name() == DomainDataset,
+		foundFirst: cursor != nil,
 	}
 
-	scanner, file, err := getScanner(inputFileName, pos)
+	cursorNeedle := needle
+	if scope != "" {
+		domainSearch.scope = []byte(scope)
+		cursorNeedle += ";" + scope
+	}
+
+	domainSearch.datasetSearch, err = openSearch(ctx, dataset, cursorNeedle, pos, cursor, domainSearch.match)
 	if err != nil {
 		return nil, err
 	}
 
-	domainSearch := DomainSearch{
-		file:      file,
-		needle:    needle,
-		needleLen: len(needle),
-		query:     []byte(query),
-		scanner:   scanner,
+	return &domainSearch, nil
+
+}
+
+// Resolve returns the addresses which name resolved to in the domain dataset.
+func Resolve(ctx context.Context, dataset Dataset, name string) ([]string, error) {
+	searcher, err := NewDomainSearch(ctx, dataset, name, ExactDomainNeedle, nil)
+	if err != nil {
+		return nil, err
 	}
+	defer searcher.Close()
 
-	return &domainSearch, nil
+	if !searcher.Next() || len(searcher.Values()) == 0 {
+		if err := searcher.Error(); err != nil && err != io.EOF {
+			return nil, err
+		}
+		return nil, ErrNotFound
+	}
 
+	return searcher.Values(), nil
 }
 
 func (ds *DomainSearch) Next() bool {
-	timeout := time.After(100 * time.Millisecond)
+	line, ok := ds.nextLine()
+	if !ok || !ds.record() {
+		return false
+	}
+
+	ds.subdomain, ds.value = ds.reconstructLine(line)
+	ds.values = appendValue(nil, ds.value)
+	if !ds.group {
+		return true
+	}
+
+	// the domain dataset holds a line for each address a name resolved to,
+	// so consecutive lines for the same name are merged into one result
+	name := append([]byte{}, nameFields(line)...)
 	for {
-		select {
-		case <-timeout:
-			fmt.Printf("TIMEOUT ON %s\n", ds.query)
-			ds.err = errors.New("timeout retrieving entry")
-			return false
-		default:
+		line, ok := ds.nextLine()
+		if !ok {
 			break
 		}
 
-		if !ds.scanner.Scan() {
-			ds.err = io.EOF
-			return false
+		if !bytes.Equal(nameFields(line), name) {
+			ds.unread(line)
+			break
 		}
 
-		if len(ds.scanner.Bytes()) < ds.needleLen {
-			continue
-		}
+		_, value := ds.reconstructLine(line)
+		ds.values = appendValue(ds.values, value)
+	}
 
-		if string(ds.scanner.Bytes()[:ds.needleLen]) != ds.needle {
-			if ds.foundFirst {
-				return false
-			} else {
-				continue
-			}
+	return true
+}
+
+func (ds *DomainSearch) match(line []byte) lineAction {
+	// the search starts at the first line of the registered domain, so
+	// there is nothing left to find once past it
+	if !bytes.HasPrefix(line, ds.key) {
+		return stopSearch
+	}
+
+	if !bytes.HasPrefix(line, ds.needle) {
+		if ds.foundFirst {
+			return stopSearch
 		}
+		return skipLine
+	}
 
-		ds.foundFirst = true
-		ds.subdomain = reconstructDomainLine(ds.scanner.Bytes())
-		return true
+	ds.foundFirst = true
+	if ds.scope != nil && !inScope(line, ds.scope) {
+		return skipLine
 	}
+
+	return matchLine
+}
+
+func (ds *DomainSearch) reconstructLine(line []byte) (string, string) {
+	if ds.lineFunc != nil {
+		return ds.lineFunc(line)
+	}
+
+	return reconstructDomainLine(line)
 }
 
 func (ds *DomainSearch) Text() string {
 	return ds.subdomain
 }
 
-func (ds *DomainSearch) Close() {
-	ds.file.Close()
+// Value returns the record value stored alongside the current name, such as
+// the target of a CNAME record.
+func (ds *DomainSearch) Value() string {
+	return ds.value
 }
 
-func (ds *DomainSearch) Error() error {
-	return ds.err
+// Values returns every value stored for the current name, such as each
+// address it resolved to.
+func (ds *DomainSearch) Values() []string {
+	return ds.values
 }
 
 func (ds *DomainSearch) Skip(size int) *DomainSearch {
-	for i := 0; i < size; i++ {
-		if !ds.Next() {
-			break
-		}
-	}
-
+	Skip(ds, size)
 	return ds
 }
 
 func (ds *DomainSearch) Take(size int) []string {
+	subdomains, _ := ds.TakeValues(size)
+	return subdomains
+}
+
+// TakeValues is like Take, but also returns the values of each name.
+func (ds *DomainSearch) TakeValues(size int) ([]string, map[string][]string) {
 	subdomains := []string{}
+	values := map[string][]string{}
 	for i := 0; i < size; i++ {
 		if !ds.Next() {
 			break
 		}
 
 		subdomains = append(subdomains, ds.Text())
-
-		if ds.err == io.EOF {
-			break
-		}
-	}
-
-	return subdomains
-}
-
-func getScanner(fileName string, pos int64) (*bufio.Scanner, *os.File, error) {
-	inputFile, err := os.Open(fileName)
-	if err != nil {
-		return nil, inputFile, err
+		values[ds.Text()] = ds.Values()
 	}
 
-	inputFile.Seek(int64(pos), 0)
-
-	scanner := bufio.NewScanner(inputFile)
-	scanner.Buffer(make([]byte, 10240), 10240)
-	return scanner, inputFile, nil
+	return subdomains, values
 }
 
 func FullDomainNeedle(queryDomain parser.Domain) (string, error) {
@@ -205,15 +217,44 @@ func FullDomainNeedle(queryDomain parser.Domain) (string, error) {
 
 }
 
+// ExactDomainNeedle matches only the queried name itself
+func ExactDomainNeedle(queryDomain parser.Domain) (string, error) {
+	needle := fmt.Sprintf("%s,%s,%s,", queryDomain.Domain, queryDomain.TLD, queryDomain.Subdomain)
+	return needle, nil
+}
+
 func DomainNeedle(queryDomain parser.Domain) (string, error) {
 	needle := fmt.Sprintf("%s,", queryDomain.Domain)
 	return needle, nil
 }
 
-func reconstructDomainLine(line []byte) string {
+// nameFields returns the `domain,tld,subdomain` part of a line.
+func nameFields(line []byte) []byte {
+	fields := 0
+	for i, c := range line {
+		if c == ',' {
+			fields++
+			if fields == 3 {
+				return line[:i]
+			}
+		}
+	}
+
+	return line
+}
+
+func appendValue(values []string, value string) []string {
+	if value == "" {
+		return values
+	}
+
+	return append(values, value)
+}
+
+func reconstructDomainLine(line []byte) (string, string) {
 	lineStr := string(line)
 
-	parts := strings.Split(lineStr, ",")
+	parts := strings.SplitN(lineStr, ",", 4)
 
 	var subdomain string
 	if len(parts[2]) == 0 {
@@ -223,6 +264,11 @@ func reconstructDomainLine(line []byte) string {
 		subdomain = fmt.Sprintf("%s.%s.%s", parts[2], parts[0], parts[1])
 	}
 
-	return subdomain
+	var value string
+	if len(parts) == 4 {
+		value = strings.TrimRight(parts[3], "\n")
+	}
+
+	return subdomain, value
 
 }
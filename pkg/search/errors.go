@@ -0,0 +1,51 @@
+package search
+
+import "errors"
+
+// ErrNotFound is returned when a query has no results.
+var ErrNotFound = errors.New("no results found")
+
+// ErrInvalidQuery is matched by the errors of queries which can never
+// succeed, such as a malformed address.
+var ErrInvalidQuery = errors.New("invalid query")
+
+var errBlankQuery = &QueryError{Reason: "query cannot be blank"}
+
+// ErrUnavailable is matched by the errors returned when a dataset or its
+// index cannot be read, or has not been configured.
+var ErrUnavailable = errors.New("backend unavailable")
+
+// QueryError describes why a query is invalid, and matches ErrInvalidQuery.
+type QueryError struct {
+	Reason string
+}
+
+func (e *QueryError) Error() string {
+	return e.Reason
+}
+
+func (e *QueryError) Is(target error) bool {
+	return target == ErrInvalidQuery
+}
+
+// ConfigError is returned when a query needs a dataset or index which has
+// not been configured, and matches ErrUnavailable.
+type ConfigError struct {
+	What string
+}
+
+func (e *ConfigError) Error() string {
+	return e.What + " is not configured"
+}
+
+func (e *ConfigError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+func (e *IOError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+func (e *IndexError) Is(target error) bool {
+	return target == ErrUnavailable
+}
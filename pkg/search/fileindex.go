@@ -0,0 +1,118 @@
+package search
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+)
+
+// FileIndex serves lookups from a sorted index file written by
+// `crobat2index -backend file`. The file starts with metadata lines of the
+// form `#key,value`, followed by `key,offset` lines in dataset order.
+// Lookups are a binary search over the file, so nothing is held in memory.
+type FileIndex struct {
+	file    *os.File
+	start   int64
+	size    int64
+	meta    map[string]string
+	compare func(a []byte, b []byte) int
+}
+
+func OpenFileIndex(fileName string) (*FileIndex, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	fi := FileIndex{
+		file:    file,
+		size:    info.Size(),
+		meta:    map[string]string{},
+		compare: bytes.Compare,
+	}
+
+	for fi.start < fi.size {
+		line, err := readLine(file, fi.start)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		if len(line) == 0 || line[0] != '#' {
+			break
+		}
+
+		parts := strings.SplitN(string(line[1:]), ",", 2)
+		if len(parts) == 2 {
+			fi.meta[parts[0]] = parts[1]
+		}
+		fi.start = fi.start + int64(len(line)) + 1
+	}
+
+	if fi.meta["order"] == "numeric" {
+		fi.compare = compareNumeric
+	}
+
+	return &fi, nil
+}
+
+// Meta returns a value from the header of the index file.
+func (fi *FileIndex) Meta(key string) (string, error) {
+	value, exists := fi.meta[key]
+	if !exists {
+		return "", errKeyNotFound
+	}
+
+	return value, nil
+}
+
+func (fi *FileIndex) Get(key string) (string, error) {
+	lineKey, value, err := fi.Ceil(key)
+	if err != nil {
+		return "", err
+	}
+
+	if fi.compare([]byte(lineKey), []byte(key)) != 0 {
+		return "", errKeyNotFound
+	}
+
+	return value, nil
+}
+
+// Ceil returns the first key at or after key, along with its value.
+func (fi *FileIndex) Ceil(key string) (string, string, error) {
+	needle := []byte(key)
+	pos, err := bisect(fi.file, fi.start, fi.size, func(line []byte) int {
+		return fi.compare(firstField(line), needle)
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	if pos >= fi.size {
+		return "", "", errKeyNotFound
+	}
+
+	line, err := readLine(fi.file, pos)
+	if err != nil {
+		return "", "", err
+	}
+
+	lineKey := firstField(line)
+	if len(lineKey) == len(line) {
+		return "", "", errors.New("malformed index line")
+	}
+
+	return string(lineKey), string(line[len(lineKey)+1:]), nil
+}
+
+func (fi *FileIndex) Close() error {
+	return fi.file.Close()
+}
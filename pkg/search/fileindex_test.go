@@ -0,0 +1,69 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFileIndex(t *testing.T, contents string) *FileIndex {
+	t.Helper()
+
+	fileName := filepath.Join(t.TempDir(), "domain.idx")
+	if err := os.WriteFile(fileName, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := OpenFileIndex(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fi.Close() })
+
+	return fi
+}
+
+func TestFileIndexGet(t *testing.T) {
+	fi := writeFileIndex(t, "#order,lexical\n#crobat:reverse:bucket,24\nexample,0:10:1\norder,10:40:2\n")
+
+	tests := []struct {
+		key   string
+		value string
+		err   error
+	}{
+		{key: "example", value: "0:10:1"},
+		// header keys must not shadow dataset keys of the same name
+		{key: "order", value: "10:40:2"},
+		{key: ReverseBucketKey, err: errKeyNotFound},
+		{key: "missing", err: errKeyNotFound},
+	}
+
+	for _, test := range tests {
+		value, err := fi.Get(test.key)
+		if err != test.err || value != test.value {
+			t.Errorf("Get(%q) = %q, %v, want %q, %v", test.key, value, err, test.value, test.err)
+		}
+	}
+}
+
+func TestFileIndexMeta(t *testing.T) {
+	fi := writeFileIndex(t, "#order,lexical\n#crobat:reverse:bucket,24\norder,10:40:2\n")
+
+	if value, err := fi.Meta("order"); err != nil || value != "lexical" {
+		t.Errorf("Meta(order) = %q, %v, want lexical", value, err)
+	}
+	if _, err := fi.Meta("missing"); err != errKeyNotFound {
+		t.Errorf("Meta(missing) = %v, want errKeyNotFound", err)
+	}
+
+	bucket, err := ReverseBucket(fi)
+	if err != nil || bucket != 24 {
+		t.Errorf("ReverseBucket = %d, %v, want 24", bucket, err)
+	}
+
+	// an index without the header uses the legacy buckets
+	legacy := writeFileIndex(t, "#order,numeric\n100,0:10:1\n")
+	if bucket, err := ReverseBucket(legacy); err != nil || bucket != 0 {
+		t.Errorf("ReverseBucket of a legacy index = %d, %v, want 0", bucket, err)
+	}
+}
@@ -2,34 +2,468 @@ package search
 
 import (
 	"context"
-	"github.com/go-redis/redis/v8"
-	"strconv"
 	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
 )
 
-var redisClient *redis.Client
-func init() {
-	redisClient = newRedisClient()
+// Index maps a dataset key to the byte offset at which its lines begin.
+type Index interface {
+	Get(key string) (string, error)
+	Close() error
 }
 
-func newRedisClient() *redis.Client {
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		PoolSize: 64,
-		DB:       0,
-	})
+// OrderedIndex is implemented by indexes which store their keys in dataset
+// order, and can therefore find the first key at or after a given key.
+type OrderedIndex interface {
+	Ceil(key string) (string, string, error)
+}
+
+// MetaIndex is implemented by indexes which hold metadata apart from their
+// keys, such as the header of an index file, so that it cannot be mistaken
+// for a key of the dataset.
+type MetaIndex interface {
+	Meta(key string) (string, error)
+}
+
+// getMeta returns the metadata stored under key, which indexes without a
+// separate store for it hold as an ordinary key.
+func getMeta(idx Index, key string) (string, error) {
+	if mi, ok := idx.(MetaIndex); ok {
+		return mi.Meta(key)
+	}
+
+	return idx.Get(key)
+}
+
+// MultiIndex is implemented by indexes which can look up many keys in a
+// single round trip. Values for keys which do not exist are left empty.
+type MultiIndex interface {
+	GetMany(keys []string) ([]string, error)
+}
+
+const multiGetBatchSize = 1024
+
+// maxBucketProbes limits how many buckets are looked up individually when
+// searching an unordered index for the start of a range.
+const maxBucketProbes = 1 << 22
+
+var errKeyNotFound = errors.New("key not found")
+
+// errNotCounted is returned when an index was built without recording how
+// many results each key holds.
+var errNotCounted = errors.New("index does not record counts")
+
+// Names of the datasets which can be searched, each with its own index
+const (
+	DomainDataset       = "domain"
+	ReverseDataset      = "reverse"
+	Reverse6Dataset     = "reverse6"
+	CNAMEDataset        = "cname"
+	CNAMEReverseDataset = "cname_reverse"
+	NSDataset           = "ns"
+	NSReverseDataset    = "ns_reverse"
+	MXDataset           = "mx"
+	MXReverseDataset    = "mx_reverse"
+	// ZoneDataset holds the domain dataset with the labels of each subdomain
+	// reversed, so that the names under a sub-zone are stored together
+	ZoneDataset = "zone"
+	// SuffixDataset is keyed by public suffix, so that the registered domains
+	// under a suffix are stored together
+	SuffixDataset = "suffix"
+)
+
+// Datasets lists every dataset which can be indexed
+var Datasets = []string{
+	DomainDataset, ReverseDataset, Reverse6Dataset,
+	CNAMEDataset, CNAMEReverseDataset,
+	NSDataset, NSReverseDataset,
+	MXDataset, MXReverseDataset,
+	ZoneDataset, SuffixDataset,
+}
+
+// datasetKey namespaces the index keys of a dataset, so that datasets can
+// share a backend. The domain and reverse keys are left bare for
+// compatibility with existing indexes.
+func datasetKey(dataset string, key string) string {
+	if dataset == DomainDataset || dataset == ReverseDataset {
+		return key
+	}
+
+	return dataset + ":" + key
+}
+
+// Redis deployments which NewRedisClient can connect to
+const (
+	RedisStandalone = "standalone"
+	RedisSentinel   = "sentinel"
+	RedisCluster    = "cluster"
+)
+
+type RedisIndex struct {
+	client redis.UniversalClient
+}
+
+func NewRedisIndex(client redis.UniversalClient) *RedisIndex {
+	return &RedisIndex{client: client}
+}
+
+// NewRedisClient connects to a Redis deployment of the given mode. Sentinel
+// mode finds the master named by options.MasterName through the sentinels
+// in options.Addrs, and cluster mode discovers the cluster from its nodes.
+func NewRedisClient(mode string, options *redis.UniversalOptions) (redis.UniversalClient, error) {
+	switch mode {
+	case "", RedisStandalone:
+		if len(options.Addrs) > 1 {
+			return nil, errors.New("standalone redis takes a single address, use sentinel or cluster mode for more")
+		}
+		return redis.NewClient(options.Simple()), nil
+	case RedisSentinel:
+		if options.MasterName == "" {
+			return nil, errors.New("sentinel mode requires the name of the master")
+		}
+		return redis.NewFailoverClient(options.Failover()), nil
+	case RedisCluster:
+		return redis.NewClusterClient(options.Cluster()), nil
+	default:
+		return nil, fmt.Errorf("redis mode must be either 'standalone', 'sentinel' or 'cluster', got %s", mode)
+	}
+}
+
+// Ping checks that Redis can be reached, and in cluster mode that every
+// shard can be.
+func (ri *RedisIndex) Ping(ctx context.Context) error {
+	if cluster, ok := ri.client.(*redis.ClusterClient); ok {
+		if err := cluster.Ping(ctx).Err(); err != nil {
+			return err
+		}
+		return cluster.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+			return shard.Ping(ctx).Err()
+		})
+	}
+
+	return ri.client.Ping(ctx).Err()
+}
+
+func (ri *RedisIndex) Get(key string) (string, error) {
+	val, err := ri.client.Get(context.Background(), key).Result()
+	if err == redis.Nil {
+		return "", errKeyNotFound
+	}
+
+	return val, err
+}
 
-	return rdb
+func (ri *RedisIndex) GetMany(keys []string) ([]string, error) {
+	// keys in a cluster live on different shards, which MGET cannot span
+	if _, ok := ri.client.(*redis.ClusterClient); ok {
+		return ri.pipelineGet(keys)
+	}
+
+	vals, err := ri.client.MGet(context.Background(), keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]string, len(keys))
+	for i, val := range vals {
+		if val != nil {
+			values[i] = val.(string)
+		}
+	}
+
+	return values, nil
 }
 
-func getPos(key string) (int64, error) {
+func (ri *RedisIndex) pipelineGet(keys []string) ([]string, error) {
 	ctx := context.Background()
-	val, err := redisClient.Get(ctx, key).Result()
+	pipe := ri.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Get(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	values := make([]string, len(keys))
+	for i, cmd := range cmds {
+		val, err := cmd.Result()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+		values[i] = val
+	}
+
+	return values, nil
+}
+
+func (ri *RedisIndex) Close() error {
+	return ri.client.Close()
+}
+
+// FallbackIndex answers lookups from Primary, and uses Fallback whenever
+// Primary fails for a reason other than the key not existing.
+type FallbackIndex struct {
+	Primary  Index
+	Fallback Index
+}
+
+func (fi *FallbackIndex) Get(key string) (string, error) {
+	val, err := fi.Primary.Get(key)
+	if err != nil && err != errKeyNotFound {
+		return fi.Fallback.Get(key)
+	}
+
+	return val, err
+}
+
+func (fi *FallbackIndex) Meta(key string) (string, error) {
+	val, err := getMeta(fi.Primary, key)
+	if err != nil && err != errKeyNotFound {
+		return getMeta(fi.Fallback, key)
+	}
+
+	return val, err
+}
+
+func (fi *FallbackIndex) GetMany(keys []string) ([]string, error) {
+	values, err := getMany(fi.Primary, keys)
 	if err != nil {
-		return 0, errors.New("no results found")
+		return getMany(fi.Fallback, keys)
+	}
+
+	return values, nil
+}
+
+// Ceil returns the first key at or after key from Primary, and from Fallback
+// whenever Primary fails for a reason other than there being no such key.
+// Both must be ordered, which orderedIndex checks before it is used.
+func (fi *FallbackIndex) Ceil(key string) (string, string, error) {
+	primary, primaryOK := fi.Primary.(OrderedIndex)
+	fallback, fallbackOK := fi.Fallback.(OrderedIndex)
+	if !primaryOK || !fallbackOK {
+		return "", "", errors.New("fallback index is not ordered")
+	}
+
+	lineKey, val, err := primary.Ceil(key)
+	if err != nil && err != errKeyNotFound {
+		return fallback.Ceil(key)
+	}
+
+	return lineKey, val, err
+}
+
+func (fi *FallbackIndex) Close() error {
+	fallbackErr := fi.Fallback.Close()
+	err := fi.Primary.Close()
+	if err != nil && fallbackErr != nil {
+		return fmt.Errorf("%w, and closing the fallback index: %v", err, fallbackErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	return fallbackErr
+}
+
+// orderedIndex returns idx as an OrderedIndex when it can find the first key
+// at or after another. A FallbackIndex can only when both of its indexes can.
+func orderedIndex(idx Index) (OrderedIndex, bool) {
+	if fi, ok := idx.(*FallbackIndex); ok {
+		_, primaryOK := orderedIndex(fi.Primary)
+		_, fallbackOK := orderedIndex(fi.Fallback)
+		return fi, primaryOK && fallbackOK
+	}
+
+	ordered, ok := idx.(OrderedIndex)
+	return ordered, ok
+}
+
+func getPos(idx Index, key string) (int64, error) {
+	if idx == nil {
+		return 0, &ConfigError{What: "index for this dataset"}
+	}
+
+	val, err := idx.Get(key)
+	if err == errKeyNotFound {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, &IndexError{Err: err}
+	}
+
+	return parseKeyStats(val).start, nil
+}
+
+// keyStats is what the index records about the lines stored under a key,
+// written by crobat2index as `start:end:count`. Indexes built before counts
+// were recorded hold only the start offset.
+type keyStats struct {
+	start int64
+	end   int64
+	// count is the number of results a query for the whole key returns
+	count   int64
+	counted bool
+}
+
+func parseKeyStats(val string) keyStats {
+	fields := strings.Split(val, ":")
+	start, _ := strconv.ParseInt(fields[0], 10, 64)
+	if len(fields) != 3 {
+		return keyStats{start: start}
+	}
+
+	end, errEnd := strconv.ParseInt(fields[1], 10, 64)
+	count, errCount := strconv.ParseInt(fields[2], 10, 64)
+	return keyStats{
+		start:   start,
+		end:     end,
+		count:   count,
+		counted: errEnd == nil && errCount == nil,
+	}
+}
+
+// getStats returns the stats recorded for key, and errNotCounted if the
+// index does not record them.
+func getStats(idx Index, key string) (keyStats, error) {
+	if idx == nil {
+		return keyStats{}, errNotCounted
+	}
+
+	val, err := idx.Get(key)
+	if err == errKeyNotFound {
+		return keyStats{counted: true}, nil
+	}
+	if err != nil {
+		return keyStats{}, &IndexError{Err: err}
+	}
+
+	stats := parseKeyStats(val)
+	if !stats.counted {
+		return keyStats{}, errNotCounted
+	}
+
+	return stats, nil
+}
+
+func getMany(idx Index, keys []string) ([]string, error) {
+	if multi, ok := idx.(MultiIndex); ok {
+		return multi.GetMany(keys)
+	}
+
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		val, err := idx.Get(key)
+		if err != nil && err != errKeyNotFound {
+			return nil, err
+		}
+		values[i] = val
+	}
+
+	return values, nil
+}
+
+// bucketRange describes the index keys covering a range of a dataset.
+type bucketRange struct {
+	from    *big.Int
+	to      *big.Int
+	key     func(bucket *big.Int) string
+	compare func(a []byte, b []byte) int
+	// bucket is the inverse of key
+	bucket func(key string) (*big.Int, bool)
+	// span returns the first and last values stored in a bucket
+	span func(bucket *big.Int) (*big.Int, *big.Int)
+}
+
+var errTooManyBuckets = &QueryError{Reason: "range spans too many index buckets, use a smaller range"}
+
+// errStopWalk is returned by walkBuckets callbacks to stop early.
+var errStopWalk = errors.New("stop walking buckets")
+
+// walkBuckets calls fn in order with each bucket between from and to
+// (inclusive) which exists in the index, along with its value.
+func walkBuckets(idx Index, buckets bucketRange, fn func(bucket *big.Int, val string) error) error {
+	one := big.NewInt(1)
+	if ordered, ok := orderedIndex(idx); ok {
+		last := []byte(buckets.key(buckets.to))
+		bucket := new(big.Int).Set(buckets.from)
+		for {
+			key, val, err := ordered.Ceil(buckets.key(bucket))
+			if err == errKeyNotFound {
+				return nil
+			}
+			if err != nil {
+				return &IndexError{Err: err}
+			}
+
+			if buckets.compare([]byte(key), last) > 0 {
+				return nil
+			}
+
+			found, ok := buckets.bucket(key)
+			if !ok {
+				return &IndexError{Err: errors.New("malformed index key " + key)}
+			}
+			if err := fn(found, val); err != nil {
+				return err
+			}
+			bucket = found.Add(found, one)
+		}
+	}
+
+	span := new(big.Int).Sub(buckets.to, buckets.from)
+	if span.Cmp(big.NewInt(maxBucketProbes)) >= 0 {
+		return errTooManyBuckets
+	}
+
+	bucket := new(big.Int).Set(buckets.from)
+	for bucket.Cmp(buckets.to) <= 0 {
+		batch := []*big.Int{}
+		keys := []string{}
+		for ; bucket.Cmp(buckets.to) <= 0 && len(keys) < multiGetBatchSize; bucket.Add(bucket, one) {
+			batch = append(batch, new(big.Int).Set(bucket))
+			keys = append(keys, buckets.key(bucket))
+		}
+
+		values, err := getMany(idx, keys)
+		if err != nil {
+			return &IndexError{Err: err}
+		}
+
+		for i, val := range values {
+			if val == "" {
+				continue
+			}
+			if err := fn(batch[i], val); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// getRangePos returns the offset of the first bucket between from and to
+// (inclusive) which exists in the index, so that ranges starting in an
+// empty bucket still find the data that follows.
+func getRangePos(idx Index, buckets bucketRange) (int64, error) {
+	pos := int64(-1)
+	err := walkBuckets(idx, buckets, func(bucket *big.Int, val string) error {
+		pos = parseKeyStats(val).start
+		return errStopWalk
+	})
+	if err != nil && err != errStopWalk {
+		return 0, err
+	}
+
+	if pos == -1 {
+		return 0, ErrNotFound
 	}
 
-	valInt, _ := strconv.ParseInt(val, 10, 64)
-	return valInt, nil
+	return pos, nil
 }
@@ -0,0 +1,86 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFallbackIndexRange(t *testing.T) {
+	dataset := writeDataset(t, Reverse6Dataset,
+		"20010db8000000000000000000000001,a.example.com",
+		"20010db8000000000000000000000002,b.example.com",
+		"20010db8000000010000000000000001,c.example.com",
+	)
+	fallback, err := NewDatasetBisectIndex(Reverse6Dataset, dataset.fileName, 0, 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fallback.Close() })
+	indexed := NewFileDataset(Reverse6Dataset, dataset.fileName, &FallbackIndex{Primary: dataset.Index(), Fallback: fallback})
+
+	// the range spans far more buckets than could be probed one at a time,
+	// so it is only found when the fallback index is ordered
+	searcher, err := NewReverseSearch(context.Background(), indexed, "2001:db8::/64", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer searcher.Close()
+
+	var domains []string
+	for searcher.Next() {
+		domains = append(domains, searcher.Result().Domain)
+	}
+	if err := searcher.Error(); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if want := []string{"a.example.com", "b.example.com"}; !reflect.DeepEqual(domains, want) {
+		t.Errorf("domains = %v, want %v", domains, want)
+	}
+}
+
+// closeErrorIndex fails to close, as a connection which has already dropped
+// would.
+type closeErrorIndex struct {
+	Index
+	err error
+}
+
+func (ci *closeErrorIndex) Close() error {
+	return ci.err
+}
+
+func TestFallbackIndexClose(t *testing.T) {
+	errPrimary, errFallback := errors.New("primary"), errors.New("fallback")
+
+	tests := []struct {
+		primary, fallback error
+	}{
+		{primary: errPrimary},
+		{fallback: errFallback},
+		{primary: errPrimary, fallback: errFallback},
+	}
+
+	for _, test := range tests {
+		fi := &FallbackIndex{
+			Primary:  &closeErrorIndex{err: test.primary},
+			Fallback: &closeErrorIndex{err: test.fallback},
+		}
+
+		err := fi.Close()
+		if test.primary != nil && !errors.Is(err, test.primary) {
+			t.Errorf("Close = %v, want the primary's error", err)
+		}
+		if test.fallback != nil && (err == nil || !strings.Contains(err.Error(), test.fallback.Error())) {
+			t.Errorf("Close = %v, want the fallback's error", err)
+		}
+	}
+
+	fi := &FallbackIndex{Primary: &closeErrorIndex{}, Fallback: &closeErrorIndex{}}
+	if err := fi.Close(); err != nil {
+		t.Errorf("Close = %v, want nil", err)
+	}
+}
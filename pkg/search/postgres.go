@@ -0,0 +1,81 @@
+package search
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// PostgresIndex reads offsets from the crobat_index table populated by
+// `crobat2index -backend postgres`.
+type PostgresIndex struct {
+	db       *sql.DB
+	stmt     *sql.Stmt
+	manyStmt *sql.Stmt
+}
+
+func NewPostgresIndex(url string) (*PostgresIndex, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := db.Prepare("SELECT value FROM crobat_index WHERE key = $1 LIMIT 1")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	manyStmt, err := db.Prepare("SELECT key, value FROM crobat_index WHERE key = ANY($1)")
+	if err != nil {
+		stmt.Close()
+		db.Close()
+		return nil, err
+	}
+
+	return &PostgresIndex{db: db, stmt: stmt, manyStmt: manyStmt}, nil
+}
+
+func (pi *PostgresIndex) Get(key string) (string, error) {
+	var value string
+	err := pi.stmt.QueryRow(key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", errKeyNotFound
+	}
+
+	return value, err
+}
+
+func (pi *PostgresIndex) GetMany(keys []string) ([]string, error) {
+	rows, err := pi.manyStmt.Query(pq.Array(keys))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := map[string]string{}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		found[key] = value
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = found[key]
+	}
+
+	return values, nil
+}
+
+func (pi *PostgresIndex) Close() error {
+	pi.manyStmt.Close()
+	pi.stmt.Close()
+	return pi.db.Close()
+}
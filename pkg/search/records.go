@@ -0,0 +1,31 @@
+package search
+
+import (
+	"context"
+
+	parser "github.com/Cgboal/DomainParser"
+)
+
+// NewRecordValueSearch finds the values of the records held by name, such as
+// the nameservers of a domain. Value returns each record value.
+func NewRecordValueSearch(ctx context.Context, dataset Dataset, name string, cursor *Cursor) (*DomainSearch, error) {
+	return NewRecordSearch(ctx, dataset, name, ExactDomainNeedle, cursor)
+}
+
+// NewRecordNameSearch finds the names holding records which point at value,
+// such as the domains served by a nameserver, using a reverse dataset. If
+// value is a registered domain, records pointing at any name beneath it are
+// returned too. Text returns the record value, and Value the name.
+func NewRecordNameSearch(ctx context.Context, dataset Dataset, value string, cursor *Cursor) (*DomainSearch, error) {
+	return NewRecordSearch(ctx, dataset, value, TargetNeedle, cursor)
+}
+
+// TargetNeedle matches every name beneath a registered domain, or the exact
+// name when the query has a subdomain.
+func TargetNeedle(queryDomain parser.Domain) (string, error) {
+	if queryDomain.Subdomain == "" {
+		return FullDomainNeedle(queryDomain)
+	}
+
+	return ExactDomainNeedle(queryDomain)
+}
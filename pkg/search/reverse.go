@@ -1,99 +1,191 @@
 package search
 
 import (
-	"bufio"
-	"errors"
-	"os"
+	"context"
 	"strconv"
-	"time"
 
 	"fmt"
-	"io"
 	"strings"
 
 	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net"
 
 	"github.com/cgboal/sonarsearch/pkg/ipconv"
-	"github.com/spf13/viper"
 )
 
 type ReverseSearch struct {
-	file          *os.File
+	*datasetSearch
 	needle        reverseNeedle
-	scanner       *bufio.Scanner
 	reverseResult reverseResult
-	err           error
-	query string
 	foundFirst    bool
+	candidate     net.IP
 }
 
+var v4InV6Prefix = net.IPv4(0, 0, 0, 0)[:12]
+
 type reverseResult struct {
 	Domain string
-	IPv4   string
+	IP     string
 }
+
+// reverseNeedle holds the range being searched for as 16 byte addresses, so
+// that IPv4 and IPv6 candidates can be compared in the same way.
 type reverseNeedle struct {
-	Min uint32
-	Max uint32
+	Min  net.IP
+	Max  net.IP
+	IPv6 bool
 }
 
-type ReverseResponse struct {
-	Results map[string][]string
-	Err     error
+// ReverseBucketKey is the index metadata key recording the prefix length
+// which the reverse index is bucketed by.
+const ReverseBucketKey = "crobat:reverse:bucket"
+
+// Reverse6BucketKey is the IPv6 equivalent of ReverseBucketKey.
+const Reverse6BucketKey = "crobat:reverse6:bucket"
+
+// Reverse6KeyPrefix namespaces the IPv6 index keys, which would otherwise
+// collide with the IPv4 ones in shared backends.
+const Reverse6KeyPrefix = Reverse6Dataset + ":"
+
+const DefaultReverse6Bucket = 48
+
+// ReverseBucket returns the prefix length which idx buckets IPs by, or 0 if
+// it uses the legacy decimal buckets.
+func ReverseBucket(idx Index) (uint32, error) {
+	return getBucket(idx, ReverseBucketKey, 0)
 }
 
-type ReverseQuery struct {
-	Query           string
-	Take            int
-	Skip            int
-	ResponseChannel chan ReverseResponse
+func Reverse6Bucket(idx Index) (uint32, error) {
+	return getBucket(idx, Reverse6BucketKey, DefaultReverse6Bucket)
 }
 
-func NewReversePool(requests <-chan ReverseQuery) {
-	for i := 0; i < 5; i++ {
-		go startReverseWorker(requests)
+func getBucket(idx Index, key string, defaultPrefixLen uint32) (uint32, error) {
+	val, err := getMeta(idx, key)
+	if err == errKeyNotFound {
+		return defaultPrefixLen, nil
 	}
+
+	if err != nil {
+		return 0, &IndexError{Err: err}
+	}
+
+	prefixLen, err := strconv.ParseUint(val, 10, 32)
+	return uint32(prefixLen), err
 }
 
-func startReverseWorker(requests <-chan ReverseQuery) {
-	for query := range requests {
-		searcher, err := NewReverseSearch(viper.GetString("reverse_file"), query.Query)
+func reverseBucket(IPv4Int uint32, prefixLen uint32) uint32 {
+	if prefixLen == 0 {
+		return ipconv.RoundDecIP(IPv4Int, 10)
+	}
+
+	return ipconv.PrefixBucket(IPv4Int, prefixLen)
+}
+
+// reverse6Key returns the index key of the prefixLen bucket holding the
+// IPv6 address in IPv6Hex.
+func reverse6Key(IPv6Hex []byte, prefixLen uint32) []byte {
+	return append([]byte(Reverse6KeyPrefix), IPv6Hex[:prefixLen/4]...)
+}
+
+func newReverseRange(idx Index, needle reverseNeedle) (bucketRange, error) {
+	one := big.NewInt(1)
+	if needle.IPv6 {
+		prefixLen, err := Reverse6Bucket(idx)
 		if err != nil {
-			query.ResponseChannel <- ReverseResponse{
-				Err: err,
-			}
-			continue
+			return bucketRange{}, err
 		}
 
-		results := searcher.Skip(query.Skip).Take(query.Take)
+		shift := uint(128 - prefixLen)
+		return bucketRange{
+			from: new(big.Int).Rsh(new(big.Int).SetBytes(needle.Min), shift),
+			to:   new(big.Int).Rsh(new(big.Int).SetBytes(needle.Max), shift),
+			key: func(bucket *big.Int) string {
+				return fmt.Sprintf("%s%0*x", Reverse6KeyPrefix, prefixLen/4, bucket)
+			},
+			compare: bytes.Compare,
+			bucket: func(key string) (*big.Int, bool) {
+				return new(big.Int).SetString(strings.TrimPrefix(key, Reverse6KeyPrefix), 16)
+			},
+			span: func(bucket *big.Int) (*big.Int, *big.Int) {
+				lo := new(big.Int).Lsh(bucket, shift)
+				hi := new(big.Int).Add(lo, new(big.Int).Sub(new(big.Int).Lsh(one, shift), one))
+				return lo, hi
+			},
+		}, nil
+	}
 
-		query.ResponseChannel <- ReverseResponse{
-			Results: results,
-			Err:     nil,
-		}
-		searcher.Close()
+	prefixLen, err := ReverseBucket(idx)
+	if err != nil {
+		return bucketRange{}, err
 	}
 
+	min := binary.BigEndian.Uint32(needle.Min.To4())
+	max := binary.BigEndian.Uint32(needle.Max.To4())
+	return bucketRange{
+		from: new(big.Int).SetUint64(uint64(reverseBucket(min, prefixLen))),
+		to:   new(big.Int).SetUint64(uint64(reverseBucket(max, prefixLen))),
+		key: func(bucket *big.Int) string {
+			return bucket.String()
+		},
+		compare: compareNumeric,
+		bucket: func(key string) (*big.Int, bool) {
+			return new(big.Int).SetString(key, 10)
+		},
+		span: func(bucket *big.Int) (*big.Int, *big.Int) {
+			// legacy buckets hold runs of ten addresses
+			size := big.NewInt(10)
+			if prefixLen != 0 {
+				size = new(big.Int).Lsh(one, uint(32-prefixLen))
+			}
+			lo := new(big.Int).Mul(bucket, size)
+			hi := new(big.Int).Add(lo, new(big.Int).Sub(size, one))
+			return lo, hi
+		},
+	}, nil
 }
+
 func newReverseNeedle(query string) (reverseNeedle, error) {
+	if strings.Contains(query, ":") {
+		if !strings.Contains(query, "/") {
+			query = query + "/128"
+		}
+		min, max, err := ipconv.CIDRMinMax6(query)
+		if err != nil {
+			return reverseNeedle{}, &QueryError{Reason: err.Error()}
+		}
+
+		return reverseNeedle{Min: min, Max: max, IPv6: true}, nil
+	}
+
 	if !strings.Contains(query, "/") {
 		query = query + "/32"
 	}
 	min, max, err := ipconv.CIDRMinMaxInt(query)
 	if err != nil {
-		return reverseNeedle{}, err
+		return reverseNeedle{}, &QueryError{Reason: err.Error()}
 	}
 
 	needle := reverseNeedle{
-		Min: min,
-		Max: max,
+		Min: uint32ToIP(min),
+		Max: uint32ToIP(max),
 	}
 
 	return needle, nil
 }
 
-func NewReverseSearch(inputFileName string, query string) (*ReverseSearch, error) {
+func uint32ToIP(IPv4Int uint32) net.IP {
+	ip := net.IPv4(0, 0, 0, 0)
+	binary.BigEndian.PutUint32(ip[12:], IPv4Int)
+	return ip
+}
+
+func NewReverseSearch(ctx context.Context, dataset Dataset, query string, cursor *Cursor) (*ReverseSearch, error) {
 	if query == "" {
-		return nil, errors.New("query cannot be blank")
+		return nil, errBlankQuery
 	}
 
 	needle, err := newReverseNeedle(query)
@@ -101,90 +193,96 @@ func NewReverseSearch(inputFileName string, query string) (*ReverseSearch, error
 		return nil, err
 	}
 
-	needleIndex := ipconv.RoundDecIP(needle.Min, 10)
-
-	if err != nil {
-		return nil, err
+	idx := dataset.Index()
+	if idx == nil {
+		return nil, &ConfigError{What: "index for this address family"}
 	}
 
-	needleIndexString := fmt.Sprint(needleIndex)
+	var pos int64
+	if cursor == nil {
+		buckets, err := newReverseRange(idx, needle)
+		if err != nil {
+			return nil, err
+		}
 
-	pos, err := getPos(needleIndexString)
+		pos, err = getRangePos(idx, buckets)
 
-	if err != nil {
-		return nil, err
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if pos == 0 {
-		return nil, errors.New("no results found")
+	return newReverseSearch(ctx, dataset, needle, pos, cursor)
+}
+
+func newReverseSearch(ctx context.Context, dataset Dataset, needle reverseNeedle, pos int64, cursor *Cursor) (*ReverseSearch, error) {
+	reverseSearch := ReverseSearch{
+		needle:     needle,
+		foundFirst: cursor != nil,
+		candidate:  make(net.IP, net.IPv6len),
 	}
 
-	scanner, file, err := getScanner(inputFileName, pos)
+	var err error
+	cursorNeedle := needle.Min.String() + "-" + needle.Max.String()
+	reverseSearch.datasetSearch, err = openSearch(ctx, dataset, cursorNeedle, pos, cursor, reverseSearch.match)
 	if err != nil {
 		return nil, err
 	}
 
-	reverseSearch := ReverseSearch{
-		file:       file,
-		needle:     needle,
-		scanner:    scanner,
-		query: query,
-		foundFirst: false,
-	}
-
 	return &reverseSearch, nil
 
 }
 
 func (rs *ReverseSearch) Next() bool {
-	timeout := time.After(100 * time.Millisecond)
-	for {
-		select {
-		case <-timeout:
-			fmt.Printf("TIMEOUT ON %s\n", rs.query)
-			rs.err = errors.New("timeout retrieving entry")
-			return false
-		default:
-			break
-		}
+	line, ok := rs.nextLine()
+	if !ok || !rs.record() {
+		return false
+	}
 
-		if !rs.scanner.Scan() {
-			rs.err = io.EOF
-			return false
-		}
+	delimPos := bytes.IndexByte(line, ',')
+	rs.reverseResult = reconstructReverseResult(rs.candidate, string(line[delimPos+1:]))
+	return true
+}
 
-		delimPos := bytes.IndexByte(rs.scanner.Bytes(), ',')
-		if delimPos == -1 {
-			continue
-		}
-		candidateIPv4, err := strconv.ParseUint(string(rs.scanner.Bytes()[:delimPos]), 10, 32)
-		candidateUInt32 := uint32(candidateIPv4)
-		if err != nil {
-			rs.err = err
-		}
+func (rs *ReverseSearch) match(line []byte) lineAction {
+	delimPos := bytes.IndexByte(line, ',')
+	if delimPos == -1 {
+		return skipLine
+	}
+	if err := rs.parseCandidate(line[:delimPos]); err != nil {
+		return skipLine
+	}
 
-		if candidateUInt32 < rs.needle.Min || candidateUInt32 > rs.needle.Max {
-			if rs.foundFirst {
-				return false
-			} else if candidateUInt32 > rs.needle.Max {
-				return false
-			} else {
-				continue
-			}
+	if bytes.Compare(rs.candidate, rs.needle.Min) < 0 || bytes.Compare(rs.candidate, rs.needle.Max) > 0 {
+		if rs.foundFirst || bytes.Compare(rs.candidate, rs.needle.Max) > 0 {
+			return stopSearch
 		}
-		rs.foundFirst = true
-		rs.reverseResult = reconstructReverseResult(candidateUInt32, string(rs.scanner.Bytes()[delimPos+1:]))
-		return true
+		return skipLine
 	}
+
+	rs.foundFirst = true
+	return matchLine
 }
 
-func (rs *ReverseSearch) Skip(size int) *ReverseSearch {
-	for i := 0; i < size; i++ {
-		if !rs.Next() {
-			break
+// errMalformedAddress is returned for dataset lines whose address cannot be
+// decoded, which are skipped as corrupt.
+var errMalformedAddress = errors.New("malformed address in dataset")
+
+// parseCandidate decodes the address at the start of a dataset line, which
+// is a decimal integer for IPv4 and 32 hex digits for IPv6.
+func (rs *ReverseSearch) parseCandidate(field []byte) error {
+	if rs.needle.IPv6 {
+		if len(field) != hex.EncodedLen(net.IPv6len) {
+			return errMalformedAddress
 		}
+		_, err := hex.Decode(rs.candidate, field)
+		return err
 	}
-	return rs
+
+	candidateIPv4, err := strconv.ParseUint(string(field), 10, 32)
+	copy(rs.candidate, v4InV6Prefix)
+	binary.BigEndian.PutUint32(rs.candidate[12:], uint32(candidateIPv4))
+	return err
 }
 
 func (rs *ReverseSearch) Take(size int) map[string][]string {
@@ -193,15 +291,11 @@ func (rs *ReverseSearch) Take(size int) map[string][]string {
 		if !rs.Next() {
 			break
 		}
-		_, exists := resultsMap[rs.Result().IPv4]
+		_, exists := resultsMap[rs.Result().IP]
 		if !exists {
-			resultsMap[rs.Result().IPv4] = []string{}
-		}
-		resultsMap[rs.Result().IPv4] = append(resultsMap[rs.Result().IPv4], rs.Result().Domain)
-
-		if rs.err == io.EOF {
-			break
+			resultsMap[rs.Result().IP] = []string{}
 		}
+		resultsMap[rs.Result().IP] = append(resultsMap[rs.Result().IP], rs.Result().Domain)
 	}
 
 	return resultsMap
@@ -211,20 +305,10 @@ func (rs *ReverseSearch) Result() reverseResult {
 	return rs.reverseResult
 }
 
-func (rs *ReverseSearch) Close() {
-	rs.file.Close()
-}
-
-func (rs *ReverseSearch) Error() error {
-	return rs.err
-}
-
-func reconstructReverseResult(IPv4Uint uint32, domain string) reverseResult {
-	ipv4String := ipconv.IntToIPv4(IPv4Uint)
-
+func reconstructReverseResult(ip net.IP, domain string) reverseResult {
 	reverseResult := reverseResult{
 		Domain: strings.TrimRight(domain, "\n"),
-		IPv4:   ipv4String,
+		IP:     ip.String(),
 	}
 
 	return reverseResult
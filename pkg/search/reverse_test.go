@@ -0,0 +1,78 @@
+package search
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeDataset writes a dataset of the given lines, which must already be
+// sorted, and opens it with a bisect index.
+func writeDataset(t *testing.T, name string, lines ...string) *FileDataset {
+	t.Helper()
+
+	fileName := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(fileName, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var idx *BisectIndex
+	var err error
+	switch name {
+	case Reverse6Dataset:
+		idx, err = NewDatasetBisectIndex(name, fileName, 0, 128)
+	default:
+		idx, err = NewDatasetBisectIndex(name, fileName, 0, 32)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { idx.Close() })
+
+	return NewFileDataset(name, fileName, idx)
+}
+
+func TestReverseSearchIPv6(t *testing.T) {
+	dataset := writeDataset(t, Reverse6Dataset,
+		"20010db8000000000000000000000001,a.example.com",
+		// corrupt lines with too many or too few digits are skipped
+		"20010db800000000000000000000000110,long.example.com",
+		"20010db8000000000000000000000002,b.example.com",
+		"20010db8000000000000000000000004,c.example.com",
+		"20010db800000000000000000005,short.example.com",
+	)
+
+	tests := []struct {
+		query   string
+		domains []string
+	}{
+		{query: "2001:db8::1", domains: []string{"a.example.com"}},
+		{query: "2001:0db8:0000::0001", domains: []string{"a.example.com"}},
+		{query: "2001:db8::/120", domains: []string{"a.example.com", "b.example.com", "c.example.com"}},
+	}
+
+	for _, test := range tests {
+		searcher, err := NewReverseSearch(context.Background(), dataset, test.query, nil)
+		if err != nil {
+			t.Errorf("NewReverseSearch(%s): %v", test.query, err)
+			continue
+		}
+
+		var domains []string
+		for searcher.Next() {
+			domains = append(domains, searcher.Result().Domain)
+		}
+		if err := searcher.Error(); err != nil && err != io.EOF {
+			t.Errorf("%s: %v", test.query, err)
+		}
+		searcher.Close()
+
+		if !reflect.DeepEqual(domains, test.domains) {
+			t.Errorf("%s = %v, want %v", test.query, domains, test.domains)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package search
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned when a query arrives while the scheduler's queue
+// is full. It matches ErrUnavailable, as the server cannot take the query
+// until others have finished.
+var ErrQueueFull error = queueFullError{}
+
+type queueFullError struct{}
+
+func (queueFullError) Error() string {
+	return "too many queries are waiting, try again later"
+}
+
+func (queueFullError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+// QueryType classes queries which share a concurrency limit, such as every
+// subdomain lookup.
+type QueryType string
+
+const (
+	SubdomainQuery QueryType = "subdomains"
+	ResolveQuery   QueryType = "resolve"
+	TLDQuery       QueryType = "tlds"
+	SuffixQuery    QueryType = "suffix"
+	AllQuery       QueryType = "all"
+	ReverseQuery   QueryType = "reverse"
+	RecordQuery    QueryType = "records"
+	CountQuery     QueryType = "count"
+)
+
+// BatchConcurrency bounds how many queries of a single batch run at once,
+// whether it arrives over REST or gRPC. Each query is still scheduled on its
+// own.
+const BatchConcurrency = 8
+
+// QueryTypes lists the query types which the server schedules.
+var QueryTypes = []QueryType{SubdomainQuery, ResolveQuery, TLDQuery, SuffixQuery, AllQuery, ReverseQuery, RecordQuery, CountQuery}
+
+// SchedulerLimits bounds how many queries run and wait at once.
+type SchedulerLimits struct {
+	// Concurrency bounds how many queries run at once across every type
+	Concurrency int
+	// TypeConcurrency bounds how many queries of each type run at once.
+	// Types without a limit may use half of Concurrency, so that no one type
+	// can take up every slot.
+	TypeConcurrency map[QueryType]int
+	// MaxQueued bounds how many queries wait for a slot, beyond which they
+	// are turned away with ErrQueueFull
+	MaxQueued int
+}
+
+// QueueStats describes the queries of one type seen by a Scheduler.
+type QueueStats struct {
+	Running  int   `json:"running"`
+	Queued   int   `json:"queued"`
+	Admitted int64 `json:"admitted"`
+	Rejected int64 `json:"rejected"`
+	// WaitTotal is the time admitted queries spent queued, which over
+	// Admitted gives the mean wait
+	WaitTotal time.Duration `json:"wait_total_ns"`
+	WaitMax   time.Duration `json:"wait_max_ns"`
+}
+
+// Scheduler admits queries to run once a slot is free for their type.
+// Queries wait in a queue for each type, which are served in turn, so that a
+// burst of slow queries of one type cannot starve the others.
+type Scheduler struct {
+	mu     sync.Mutex
+	limits SchedulerLimits
+	queues map[QueryType]*queryQueue
+	// order is the order queues are served in, with next the queue to
+	// serve first
+	order   []QueryType
+	next    int
+	running int
+	queued  int
+}
+
+type queryQueue struct {
+	limit   int
+	running int
+	waiting []*queuedQuery
+	stats   QueueStats
+}
+
+type queuedQuery struct {
+	ready    chan struct{}
+	queuedAt time.Time
+	admitted bool
+}
+
+// NewScheduler returns a Scheduler enforcing limits. A Concurrency of zero
+// admits every query at once.
+func NewScheduler(limits SchedulerLimits) *Scheduler {
+	return &Scheduler{
+		limits: limits,
+		queues: map[QueryType]*queryQueue{},
+	}
+}
+
+func (s *Scheduler) queue(queryType QueryType) *queryQueue {
+	queue, exists := s.queues[queryType]
+	if exists {
+		return queue
+	}
+
+	limit := s.limits.TypeConcurrency[queryType]
+	if limit <= 0 {
+		limit = s.limits.Concurrency / 2
+		if limit < 1 {
+			limit = 1
+		}
+	}
+
+	queue = &queryQueue{limit: limit}
+	s.queues[queryType] = queue
+	s.order = append(s.order, queryType)
+	return queue
+}
+
+func (s *Scheduler) canRun(queue *queryQueue) bool {
+	return s.running < s.limits.Concurrency && queue.running < queue.limit
+}
+
+// Acquire waits until a query of queryType may run, and returns the func
+// which must be called once it has finished. Queries are turned away with
+// ErrQueueFull when the queue is full, and stop waiting when ctx is done.
+func (s *Scheduler) Acquire(ctx context.Context, queryType QueryType) (func(), error) {
+	if s == nil || s.limits.Concurrency <= 0 {
+		return func() {}, nil
+	}
+
+	s.mu.Lock()
+	queue := s.queue(queryType)
+	if len(queue.waiting) == 0 && s.canRun(queue) {
+		s.admit(queue, 0)
+		s.mu.Unlock()
+		return s.releaseFunc(queue), nil
+	}
+
+	if s.queued >= s.limits.MaxQueued {
+		queue.stats.Rejected++
+		s.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+
+	query := &queuedQuery{ready: make(chan struct{}), queuedAt: time.Now()}
+	queue.waiting = append(queue.waiting, query)
+	s.queued++
+	s.mu.Unlock()
+
+	select {
+	case <-query.ready:
+		return s.releaseFunc(queue), nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	if query.admitted {
+		// the slot was handed over as the query gave up, so pass it on
+		s.mu.Unlock()
+		s.releaseFunc(queue)()
+		return nil, contextError(ctx.Err())
+	}
+
+	for i, waiting := range queue.waiting {
+		if waiting == query {
+			queue.waiting = append(queue.waiting[:i], queue.waiting[i+1:]...)
+			break
+		}
+	}
+	s.queued--
+	s.mu.Unlock()
+
+	return nil, contextError(ctx.Err())
+}
+
+func (s *Scheduler) admit(queue *queryQueue, wait time.Duration) {
+	s.running++
+	queue.running++
+	queue.stats.Admitted++
+	queue.stats.WaitTotal += wait
+	if wait > queue.stats.WaitMax {
+		queue.stats.WaitMax = wait
+	}
+}
+
+func (s *Scheduler) releaseFunc(queue *queryQueue) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			s.running--
+			queue.running--
+			s.dispatch()
+		})
+	}
+}
+
+// dispatch admits waiting queries while there are free slots, taking one
+// from each type's queue in turn.
+func (s *Scheduler) dispatch() {
+	for s.queued > 0 && s.running < s.limits.Concurrency {
+		admitted := false
+		for i := 0; i < len(s.order); i++ {
+			index := (s.next + i) % len(s.order)
+			queue := s.queues[s.order[index]]
+			if len(queue.waiting) == 0 || !s.canRun(queue) {
+				continue
+			}
+
+			query := queue.waiting[0]
+			queue.waiting = queue.waiting[1:]
+			s.queued--
+			s.admit(queue, time.Since(query.queuedAt))
+			query.admitted = true
+			close(query.ready)
+
+			s.next = index + 1
+			admitted = true
+			break
+		}
+
+		if !admitted {
+			return
+		}
+	}
+}
+
+// Stats returns the state of each type's queue.
+func (s *Scheduler) Stats() map[QueryType]QueueStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := map[QueryType]QueueStats{}
+	for queryType, queue := range s.queues {
+		queueStats := queue.stats
+		queueStats.Running = queue.running
+		queueStats.Queued = len(queue.waiting)
+		stats[queryType] = queueStats
+	}
+
+	return stats
+}
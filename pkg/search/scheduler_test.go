@@ -0,0 +1,128 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitQueued waits until n queries of queryType are waiting in s.
+func waitQueued(t *testing.T, s *Scheduler, queryType QueryType, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Stats()[queryType].Queued != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("queued = %d, want %d", s.Stats()[queryType].Queued, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerQueueFull(t *testing.T) {
+	s := NewScheduler(SchedulerLimits{Concurrency: 1})
+
+	release, err := s.Acquire(context.Background(), SubdomainQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// with no room to queue, a query arriving while the only slot is taken
+	// is turned away at once
+	_, err = s.Acquire(context.Background(), SubdomainQuery)
+	if !errors.Is(err, ErrQueueFull) || !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Acquire = %v, want ErrQueueFull", err)
+	}
+	if stats := s.Stats()[SubdomainQuery]; stats.Rejected != 1 {
+		t.Errorf("rejected = %d, want 1", stats.Rejected)
+	}
+
+	release()
+	release, err = s.Acquire(context.Background(), SubdomainQuery)
+	if err != nil {
+		t.Fatalf("Acquire after release = %v", err)
+	}
+	release()
+}
+
+func TestSchedulerCancelQueued(t *testing.T) {
+	s := NewScheduler(SchedulerLimits{Concurrency: 1, MaxQueued: 1})
+
+	release, err := s.Acquire(context.Background(), SubdomainQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		_, err := s.Acquire(ctx, SubdomainQuery)
+		errs <- err
+	}()
+	waitQueued(t, s, SubdomainQuery, 1)
+
+	cancel()
+	if err := <-errs; err != ErrCanceled {
+		t.Errorf("Acquire = %v, want ErrCanceled", err)
+	}
+	if stats := s.Stats()[SubdomainQuery]; stats.Queued != 0 || stats.Running != 1 {
+		t.Errorf("stats = %+v, want only the running query", stats)
+	}
+
+	// the cancelled query left room in the queue
+	go func() {
+		release, err := s.Acquire(context.Background(), SubdomainQuery)
+		if err == nil {
+			release()
+		}
+		errs <- err
+	}()
+	waitQueued(t, s, SubdomainQuery, 1)
+
+	release()
+	if err := <-errs; err != nil {
+		t.Errorf("Acquire = %v", err)
+	}
+}
+
+func TestSchedulerReleaseAfterError(t *testing.T) {
+	s := NewScheduler(SchedulerLimits{Concurrency: 2, MaxQueued: 1})
+
+	// a query which fails still releases its slot, and releasing twice
+	// frees only the one
+	first, err := s.Acquire(context.Background(), ReverseQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Acquire(context.Background(), SubdomainQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := s.Acquire(ctx, AllQuery); err != ErrDeadlineExceeded {
+		t.Fatalf("Acquire = %v, want ErrDeadlineExceeded", err)
+	}
+
+	first()
+	first()
+	if stats := s.Stats(); stats[ReverseQuery].Running != 0 || stats[SubdomainQuery].Running != 1 {
+		t.Errorf("stats = %+v, want one running subdomain query", stats)
+	}
+
+	// the freed slot goes to the next query
+	third, err := s.Acquire(context.Background(), AllQuery)
+	if err != nil {
+		t.Fatalf("Acquire after release = %v", err)
+	}
+	second()
+	third()
+
+	for queryType, stats := range s.Stats() {
+		if stats.Running != 0 || stats.Queued != 0 {
+			t.Errorf("%s stats = %+v, want none running or queued", queryType, stats)
+		}
+	}
+}
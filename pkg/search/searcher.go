@@ -0,0 +1,185 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"io"
+)
+
+// Searcher steps through the results of a query against a dataset.
+type Searcher interface {
+	// Next moves on to the next result, returning false once there are none
+	// left
+	Next() bool
+	// Error returns the error which stopped the search, or io.EOF if it ran
+	// to the end of the dataset
+	Error() error
+	// Cursor returns where to resume the search from, after the current
+	// result
+	Cursor() *Cursor
+	Close()
+}
+
+// Skip steps past size results of s.
+func Skip(s Searcher, size int) {
+	for i := 0; i < size; i++ {
+		if !s.Next() {
+			break
+		}
+	}
+}
+
+type lineAction int
+
+const (
+	// skipLine passes over a line, but later lines may still match
+	skipLine lineAction = iota
+	matchLine
+	// stopSearch ends the search, as no later line can match
+	stopSearch
+)
+
+// NextCursor returns the cursor to resume s from once the results read so far
+// have been returned, or nil if there are none left. It reads one result
+// ahead to find out.
+func NextCursor(s Searcher) *Cursor {
+	cursor := s.Cursor()
+	if !s.Next() {
+		return nil
+	}
+
+	return cursor
+}
+
+// datasetSearch is the scanner loop shared by each search, which decides what
+// to do with every line it reads using match.
+type datasetSearch struct {
+	reader    io.Closer
+	scanner   *lineScanner
+	match     func(line []byte) lineAction
+	peeked    []byte
+	hasPeeked bool
+	done      bool
+	err       error
+
+	// the state needed to issue cursors, where offset is that of the first
+	// line read
+	dataset      string
+	version      string
+	needle       string
+	offset       int64
+	lineOffset   int64
+	peekedOffset int64
+	// limit stops the search at an offset, such as the end of a key
+	limit int64
+}
+
+// openSearch opens dataset at pos, which should be the start of a line, or
+// where cursor left off. needle identifies the query in the cursors issued.
+func openSearch(ctx context.Context, dataset Dataset, needle string, pos int64, cursor *Cursor, match func(line []byte) lineAction) (*datasetSearch, error) {
+	version, err := dataset.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	if cursor != nil {
+		if err := cursor.check(dataset.Name(), version, needle); err != nil {
+			return nil, err
+		}
+		pos = cursor.Offset
+	}
+
+	reader, err := dataset.Open(pos)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, maxLineLength), maxLineLength)
+
+	return &datasetSearch{
+		reader:  reader,
+		scanner: newLineScanner(ctx, scanner),
+		match:   match,
+		dataset: dataset.Name(),
+		version: version,
+		needle:  needle,
+		offset:  pos,
+	}, nil
+}
+
+// nextLine returns the next matching line, which is only valid until the
+// following call.
+func (ds *datasetSearch) nextLine() ([]byte, bool) {
+	if ds.hasPeeked {
+		ds.hasPeeked = false
+		ds.lineOffset = ds.peekedOffset
+		return ds.peeked, true
+	}
+
+	for !ds.done {
+		if ds.limit > 0 && ds.offset+ds.scanner.bytesRead >= ds.limit {
+			ds.err = io.EOF
+			break
+		}
+
+		if !ds.scanner.Scan() {
+			ds.err = ds.scanner.Err()
+			break
+		}
+
+		line := ds.scanner.Bytes()
+		switch ds.match(line) {
+		case matchLine:
+			ds.lineOffset = ds.offset + ds.scanner.bytesRead - int64(len(line)) - 1
+			return line, true
+		case stopSearch:
+			ds.done = true
+		}
+	}
+
+	ds.done = true
+	return nil, false
+}
+
+// unread returns a line to the search, so that it is read again by the next
+// call to nextLine.
+func (ds *datasetSearch) unread(line []byte) {
+	ds.peeked = append(ds.peeked[:0], line...)
+	ds.peekedOffset = ds.lineOffset
+	ds.hasPeeked = true
+}
+
+// record counts a result against the budget of the query, and reports whether
+// it may be returned.
+func (ds *datasetSearch) record() bool {
+	if !ds.scanner.record() {
+		ds.err = ds.scanner.Err()
+		ds.done = true
+		return false
+	}
+
+	return true
+}
+
+func (ds *datasetSearch) Cursor() *Cursor {
+	offset := ds.offset + ds.scanner.bytesRead
+	if ds.hasPeeked {
+		offset = ds.peekedOffset
+	}
+
+	return &Cursor{
+		Dataset: ds.dataset,
+		Version: ds.version,
+		Needle:  ds.needle,
+		Offset:  offset,
+	}
+}
+
+func (ds *datasetSearch) Error() error {
+	return ds.err
+}
+
+func (ds *datasetSearch) Close() {
+	ds.reader.Close()
+}
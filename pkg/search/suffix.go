@@ -0,0 +1,119 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+)
+
+// SuffixSearch lists the registered domains under a public suffix, such as
+// gov.uk, using the suffix dataset. Its lines are laid out as
+// `tld,domain,subdomain`, so that the domains under a suffix are stored
+// together.
+type SuffixSearch struct {
+	*datasetSearch
+	suffix     string
+	needle     []byte
+	domain     string
+	subdomains int
+}
+
+func NewSuffixSearch(ctx context.Context, dataset Dataset, suffix string, cursor *Cursor) (*SuffixSearch, error) {
+	suffix = strings.Trim(suffix, ".")
+	if suffix == "" {
+		return nil, errBlankQuery
+	}
+
+	var pos int64
+	if cursor == nil {
+		var err error
+		pos, err = getPos(dataset.Index(), datasetKey(dataset.Name(), suffix))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	suffixSearch := SuffixSearch{
+		suffix: suffix,
+		needle: []byte(suffix + ","),
+	}
+
+	var err error
+	suffixSearch.datasetSearch, err = openSearch(ctx, dataset, string(suffixSearch.needle), pos, cursor, suffixSearch.match)
+	if err != nil {
+		return nil, err
+	}
+
+	return &suffixSearch, nil
+}
+
+func (ss *SuffixSearch) match(line []byte) lineAction {
+	if !bytes.HasPrefix(line, ss.needle) {
+		return stopSearch
+	}
+
+	return matchLine
+}
+
+// Next moves on to the next registered domain, counting its subdomains.
+func (ss *SuffixSearch) Next() bool {
+	line, ok := ss.nextLine()
+	if !ok || !ss.record() {
+		return false
+	}
+
+	domain, subdomain := splitSuffixLine(line[len(ss.needle):])
+	current := append([]byte{}, domain...)
+	ss.domain = string(domain) + "." + ss.suffix
+	ss.subdomains = 0
+	previous := []byte{}
+
+	for {
+		// the dataset may hold the same name more than once
+		if len(subdomain) != 0 && !bytes.Equal(subdomain, previous) {
+			ss.subdomains++
+			previous = append(previous[:0], subdomain...)
+		}
+
+		line, ok = ss.nextLine()
+		if !ok {
+			// a search cut short part way through the domain has no cursor
+			// past it, so the domain is left for the search which resumes
+			if err := ss.Error(); err != nil && err != io.EOF {
+				return false
+			}
+			return true
+		}
+
+		domain, subdomain = splitSuffixLine(line[len(ss.needle):])
+		if !bytes.Equal(domain, current) {
+			ss.unread(line)
+			return true
+		}
+	}
+}
+
+func (ss *SuffixSearch) Skip(size int) *SuffixSearch {
+	Skip(ss, size)
+	return ss
+}
+
+// Text returns the current registered domain.
+func (ss *SuffixSearch) Text() string {
+	return ss.domain
+}
+
+// Subdomains returns the number of distinct subdomains of the current domain.
+func (ss *SuffixSearch) Subdomains() int {
+	return ss.subdomains
+}
+
+func splitSuffixLine(line []byte) ([]byte, []byte) {
+	delimPos := bytes.IndexByte(line, ',')
+	if delimPos == -1 {
+		return line, nil
+	}
+
+	return line[:delimPos], firstField(line[delimPos+1:])
+}
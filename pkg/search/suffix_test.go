@@ -0,0 +1,57 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSuffixSearchResumeAfterBudget(t *testing.T) {
+	dataset := writeDataset(t, SuffixDataset,
+		"com,alpha,www",
+		"com,example,a",
+		"com,example,b",
+		"com,example,c",
+		"com,other,www",
+	)
+
+	// the budget runs out part way through example.com
+	ctx := WithBudget(context.Background(), Budget{MaxBytes: 36})
+	searcher, err := NewSuffixSearch(ctx, dataset, "com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := map[string]int{}
+	var domains []string
+	var cursor *Cursor
+	for searcher.Next() {
+		domains = append(domains, searcher.Text())
+		counts[searcher.Text()] = searcher.Subdomains()
+		cursor = searcher.Cursor()
+	}
+	if err := searcher.Error(); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Error = %v, want ErrBudgetExceeded", err)
+	}
+	searcher.Close()
+
+	// resuming after the last domain returned finds example.com once, with
+	// all of its subdomains
+	searcher, err = NewSuffixSearch(context.Background(), dataset, "com", cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer searcher.Close()
+
+	for searcher.Next() {
+		domains = append(domains, searcher.Text())
+		counts[searcher.Text()] = searcher.Subdomains()
+	}
+	if want := []string{"alpha.com", "example.com", "other.com"}; !reflect.DeepEqual(domains, want) {
+		t.Errorf("domains = %v, want %v", domains, want)
+	}
+	if counts["example.com"] != 3 {
+		t.Errorf("example.com has %d subdomains, want 3", counts["example.com"])
+	}
+}
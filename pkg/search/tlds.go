@@ -0,0 +1,117 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// TLDSearch steps through the registered domains sharing the label of a
+// query, such as example.com and example.co.uk for example.com, in dataset
+// order. Each is returned once, and its cursor resumes after every line
+// holding it, so that resumed searches do not return it again.
+type TLDSearch struct {
+	*datasetSearch
+	key    []byte
+	domain string
+}
+
+func NewTLDSearch(ctx context.Context, dataset Dataset, query string, cursor *Cursor) (*TLDSearch, error) {
+	if query == "" {
+		return nil, errBlankQuery
+	}
+
+	queryDomain := dp.ParseDomain(query)
+
+	var pos int64
+	var err error
+	if cursor == nil {
+		pos, err = getPos(dataset.Index(), datasetKey(dataset.Name(), queryDomain.Domain))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tldSearch := TLDSearch{key: []byte(queryDomain.Domain + ",")}
+
+	// the needle differs from that of subdomain searches, whose cursors may
+	// point into the middle of a registered domain
+	tldSearch.datasetSearch, err = openSearch(ctx, dataset, "tlds;"+string(tldSearch.key), pos, cursor, tldSearch.match)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tldSearch, nil
+}
+
+func (ts *TLDSearch) match(line []byte) lineAction {
+	if !bytes.HasPrefix(line, ts.key) {
+		return stopSearch
+	}
+
+	return matchLine
+}
+
+func (ts *TLDSearch) Next() bool {
+	line, ok := ts.nextLine()
+	if !ok || !ts.record() {
+		return false
+	}
+
+	// the lines of a registered domain are stored together, as the dataset
+	// is sorted by `domain,tld`
+	registered := append([]byte{}, registeredFields(line)...)
+	ts.domain = string(bytes.Replace(bytes.TrimSuffix(registered, []byte(",")), []byte(","), []byte("."), 1))
+	for {
+		line, ok := ts.nextLine()
+		if !ok {
+			break
+		}
+
+		if !bytes.HasPrefix(line, registered) {
+			ts.unread(line)
+			break
+		}
+	}
+
+	// a search cut short part way through the domain has no cursor past it,
+	// so the domain is left for the search which resumes
+	if err := ts.Error(); err != nil && err != io.EOF {
+		return false
+	}
+
+	return true
+}
+
+// Text returns the current registered domain, such as example.co.uk.
+func (ts *TLDSearch) Text() string {
+	return ts.domain
+}
+
+func (ts *TLDSearch) Take(size int) []string {
+	domains := []string{}
+	for i := 0; i < size; i++ {
+		if !ts.Next() {
+			break
+		}
+
+		domains = append(domains, ts.Text())
+	}
+
+	return domains
+}
+
+// registeredFields returns the `domain,tld,` part of a line.
+func registeredFields(line []byte) []byte {
+	fields := 0
+	for i, c := range line {
+		if c == ',' {
+			fields++
+			if fields == 2 {
+				return line[:i+1]
+			}
+		}
+	}
+
+	return line
+}
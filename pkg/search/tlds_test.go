@@ -0,0 +1,73 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTLDSearch(t *testing.T) {
+	dataset := writeDataset(t, DomainDataset,
+		"example,co.uk,mail,1.2.3.4",
+		"example,co.uk,www,1.2.3.5",
+		"example,com,a,1.2.3.6",
+		"example,com,b,1.2.3.7",
+		"example,com,c,1.2.3.8",
+		"example,org,www,1.2.3.9",
+		"examples,com,www,1.2.3.10",
+	)
+
+	searcher, err := NewTLDSearch(context.Background(), dataset, "www.example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := searcher.Take(10)
+	searcher.Close()
+
+	// each domain is returned once, in dataset order
+	want := []string{"example.co.uk", "example.com", "example.org"}
+	if !reflect.DeepEqual(all, want) {
+		t.Fatalf("Take = %v, want %v", all, want)
+	}
+
+	// paging one domain at a time returns each domain once
+	var paged []string
+	var cursor *Cursor
+	for page := 0; page < len(want)+1; page++ {
+		searcher, err := NewTLDSearch(context.Background(), dataset, "example.com", cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paged = append(paged, searcher.Take(1)...)
+		cursor = NextCursor(searcher)
+		searcher.Close()
+
+		if cursor == nil {
+			break
+		}
+		// the cursor survives being passed to a client and back
+		if cursor, err = ParseCursor(cursor.String()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(paged, want) {
+		t.Errorf("paged = %v, want %v", paged, want)
+	}
+}
+
+func TestTLDSearchCursor(t *testing.T) {
+	dataset := writeDataset(t, DomainDataset, "example,com,www,1.2.3.4")
+
+	searcher, err := NewDomainSearch(context.Background(), dataset, "example.com", DomainNeedle, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	searcher.Next()
+	cursor := searcher.Cursor()
+	searcher.Close()
+
+	// subdomain cursors may point part way through a domain
+	if _, err := NewTLDSearch(context.Background(), dataset, "example.com", cursor); err == nil {
+		t.Error("NewTLDSearch accepted the cursor of a subdomain search")
+	}
+}
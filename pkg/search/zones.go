@@ -0,0 +1,147 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	parser "github.com/Cgboal/DomainParser"
+)
+
+// ZoneLabels reverses the labels of a subdomain, terminating each with a dot,
+// so that "a.dev" becomes "dev.a.". Sorting the zone dataset by these keeps
+// every name under a sub-zone together.
+func ZoneLabels(subdomain string) string {
+	if subdomain == "" {
+		return ""
+	}
+
+	labels := strings.Split(subdomain, ".")
+	var reversed strings.Builder
+	for i := len(labels) - 1; i >= 0; i-- {
+		reversed.WriteString(labels[i])
+		reversed.WriteByte('.')
+	}
+
+	return reversed.String()
+}
+
+// ZoneNeedle matches the queried name and every name under it in the zone
+// dataset.
+func ZoneNeedle(queryDomain parser.Domain) (string, error) {
+	needle := fmt.Sprintf("%s,%s,%s", queryDomain.Domain, queryDomain.TLD, ZoneLabels(queryDomain.Subdomain))
+	return needle, nil
+}
+
+// NewSubzoneSearch finds every name under query, which may be a sub-zone such
+// as dev.example.com. Queries for a registered domain are answered from the
+// domain dataset as before. Sub-zones are found by binary searching the zone
+// dataset when one is given, or else by filtering the subdomains of the
+// registered domain.
+func NewSubzoneSearch(ctx context.Context, domains Dataset, zones Dataset, query string, cursor *Cursor) (*DomainSearch, error) {
+	if query == "" {
+		return nil, errBlankQuery
+	}
+
+	queryDomain := dp.ParseDomain(query)
+	if queryDomain.Subdomain == "" {
+		return NewDomainSearch(ctx, domains, query, FullDomainNeedle, cursor)
+	}
+
+	if zones == nil {
+		return newRecordSearch(ctx, domains, query, FullDomainNeedle, queryDomain.Subdomain, cursor)
+	}
+
+	randomZones, ok := zones.(RandomAccessDataset)
+	if !ok {
+		return nil, errors.New("zone dataset does not support binary search")
+	}
+
+	needle, err := ZoneNeedle(queryDomain)
+	if err != nil {
+		return nil, err
+	}
+
+	var pos int64
+	if cursor == nil {
+		pos, err = subzonePos(randomZones, queryDomain.Domain, []byte(needle))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	domainSearch := DomainSearch{
+		key:        []byte(queryDomain.Domain + ","),
+		needle:     []byte(needle),
+		group:      true,
+		lineFunc:   reconstructZoneLine,
+		foundFirst: true,
+	}
+
+	domainSearch.datasetSearch, err = openSearch(ctx, zones, needle, pos, cursor, domainSearch.match)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domainSearch, nil
+}
+
+// subzonePos finds the first line of the zone dataset at or after needle.
+func subzonePos(zones RandomAccessDataset, domain string, needle []byte) (int64, error) {
+	// the index only narrows the search down to the registered domain, and
+	// the zone dataset can be searched without one
+	var lo int64
+	if idx := zones.Index(); idx != nil {
+		var err error
+		lo, err = getPos(idx, datasetKey(ZoneDataset, domain))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return zonePos(zones, lo, needle)
+}
+
+// zonePos binary searches the zone dataset from lo for the first line at or
+// after needle.
+func zonePos(zones RandomAccessDataset, lo int64, needle []byte) (int64, error) {
+	reader, size, err := zones.OpenReaderAt()
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	pos, err := bisect(reader, lo, size, func(line []byte) int {
+		return bytes.Compare(line, needle)
+	})
+	if err != nil {
+		return 0, &IOError{Err: err}
+	}
+
+	return pos, nil
+}
+
+// inScope reports whether the subdomain of line is scope or lies under it.
+func inScope(line []byte, scope []byte) bool {
+	name := nameFields(line)
+	subdomain := name[bytes.LastIndexByte(name, ',')+1:]
+	if !bytes.HasSuffix(subdomain, scope) {
+		return false
+	}
+
+	return len(subdomain) == len(scope) || subdomain[len(subdomain)-len(scope)-1] == '.'
+}
+
+func reconstructZoneLine(line []byte) (string, string) {
+	parts := strings.SplitN(string(line), ",", 4)
+
+	labels := strings.Split(strings.TrimSuffix(parts[2], "."), ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	parts[2] = strings.Join(labels, ".")
+
+	return reconstructDomainLine([]byte(strings.Join(parts, ",")))
+}
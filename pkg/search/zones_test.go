@@ -0,0 +1,30 @@
+package search
+
+import "testing"
+
+func TestZoneLabels(t *testing.T) {
+	tests := []struct {
+		subdomain string
+		labels    string
+	}{
+		{subdomain: "", labels: ""},
+		{subdomain: "www", labels: "www."},
+		{subdomain: "x.a.dev", labels: "dev.a.x."},
+	}
+
+	for _, test := range tests {
+		labels := ZoneLabels(test.subdomain)
+		if labels != test.labels {
+			t.Errorf("ZoneLabels(%q) = %q, want %q", test.subdomain, labels, test.labels)
+		}
+
+		// zone lines are turned back into domain lines when searched
+		if test.subdomain == "" {
+			continue
+		}
+		name, _ := reconstructZoneLine([]byte("example,com," + labels + ",1.2.3.4"))
+		if want := test.subdomain + ".example.com"; name != want {
+			t.Errorf("reconstructZoneLine = %q, want %q", name, want)
+		}
+	}
+}
@@ -18,9 +18,38 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type CrobatClient interface {
 	GetSubdomains(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_GetSubdomainsClient, error)
+	// Returns the addresses which a name resolved to
+	Resolve(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_ResolveClient, error)
 	GetTLDs(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_GetTLDsClient, error)
+	// Lists the registered domains under a public suffix, such as gov.uk, along
+	// with how many subdomains each has
+	GetSuffixDomains(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_GetSuffixDomainsClient, error)
 	ReverseDNS(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_ReverseDNSClient, error)
 	ReverseDNSRange(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_ReverseDNSRangeClient, error)
+	// Follows the CNAME records for a name, returning each hop in order
+	GetCNAMEChain(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_GetCNAMEChainClient, error)
+	// Finds the names which are CNAMEs of a target, or of any name beneath it
+	// when the target is a registered domain
+	GetCNAMEAliases(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_GetCNAMEAliasesClient, error)
+	// Finds the nameservers of a name
+	GetNS(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_GetNSClient, error)
+	// Finds the names served by a nameserver, or by any nameserver beneath it
+	// when the query is a registered domain
+	GetNSDomains(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_GetNSDomainsClient, error)
+	// Finds the mail servers of a name
+	GetMX(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_GetMXClient, error)
+	// Finds the names using a mail server, or any mail server beneath it when
+	// the query is a registered domain
+	GetMXDomains(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_GetMXDomainsClient, error)
+	// Count the results which GetSubdomains, GetSuffixDomains and ReverseDNS
+	// or ReverseDNSRange would return for the same query
+	CountSubdomains(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*Count, error)
+	CountSuffixDomains(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*Count, error)
+	CountReverseDNS(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*Count, error)
+	// Runs many queries over a single stream. Results are tagged with the query
+	// they belong to, and a query which fails reports its error without ending
+	// the stream
+	Batch(ctx context.Context, opts ...grpc.CallOption) (Crobat_BatchClient, error)
 }
 
 type crobatClient struct {
@@ -63,8 +92,40 @@ func (x *crobatGetSubdomainsClient) Recv() (*Domain, error) {
 	return m, nil
 }
 
+func (c *crobatClient) Resolve(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_ResolveClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Crobat_serviceDesc.Streams[1], "/proto.Crobat/Resolve", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &crobatResolveClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Crobat_ResolveClient interface {
+	Recv() (*Domain, error)
+	grpc.ClientStream
+}
+
+type crobatResolveClient struct {
+	grpc.ClientStream
+}
+
+func (x *crobatResolveClient) Recv() (*Domain, error) {
+	m := new(Domain)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *crobatClient) GetTLDs(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_GetTLDsClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_Crobat_serviceDesc.Streams[1], "/proto.Crobat/GetTLDs", opts...)
+	stream, err := c.cc.NewStream(ctx, &_Crobat_serviceDesc.Streams[2], "/proto.Crobat/GetTLDs", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -95,8 +156,40 @@ func (x *crobatGetTLDsClient) Recv() (*Domain, error) {
 	return m, nil
 }
 
+func (c *crobatClient) GetSuffixDomains(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_GetSuffixDomainsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Crobat_serviceDesc.Streams[3], "/proto.Crobat/GetSuffixDomains", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &crobatGetSuffixDomainsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Crobat_GetSuffixDomainsClient interface {
+	Recv() (*Domain, error)
+	grpc.ClientStream
+}
+
+type crobatGetSuffixDomainsClient struct {
+	grpc.ClientStream
+}
+
+func (x *crobatGetSuffixDomainsClient) Recv() (*Domain, error) {
+	m := new(Domain)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *crobatClient) ReverseDNS(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_ReverseDNSClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_Crobat_serviceDesc.Streams[2], "/proto.Crobat/ReverseDNS", opts...)
+	stream, err := c.cc.NewStream(ctx, &_Crobat_serviceDesc.Streams[4], "/proto.Crobat/ReverseDNS", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -128,7 +221,7 @@ func (x *crobatReverseDNSClient) Recv() (*Domain, error) {
 }
 
 func (c *crobatClient) ReverseDNSRange(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_ReverseDNSRangeClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_Crobat_serviceDesc.Streams[3], "/proto.Crobat/ReverseDNSRange", opts...)
+	stream, err := c.cc.NewStream(ctx, &_Crobat_serviceDesc.Streams[5], "/proto.Crobat/ReverseDNSRange", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -159,14 +252,293 @@ func (x *crobatReverseDNSRangeClient) Recv() (*Domain, error) {
 	return m, nil
 }
 
+func (c *crobatClient) GetCNAMEChain(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_GetCNAMEChainClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Crobat_serviceDesc.Streams[6], "/proto.Crobat/GetCNAMEChain", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &crobatGetCNAMEChainClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Crobat_GetCNAMEChainClient interface {
+	Recv() (*Domain, error)
+	grpc.ClientStream
+}
+
+type crobatGetCNAMEChainClient struct {
+	grpc.ClientStream
+}
+
+func (x *crobatGetCNAMEChainClient) Recv() (*Domain, error) {
+	m := new(Domain)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *crobatClient) GetCNAMEAliases(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_GetCNAMEAliasesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Crobat_serviceDesc.Streams[7], "/proto.Crobat/GetCNAMEAliases", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &crobatGetCNAMEAliasesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Crobat_GetCNAMEAliasesClient interface {
+	Recv() (*Domain, error)
+	grpc.ClientStream
+}
+
+type crobatGetCNAMEAliasesClient struct {
+	grpc.ClientStream
+}
+
+func (x *crobatGetCNAMEAliasesClient) Recv() (*Domain, error) {
+	m := new(Domain)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *crobatClient) GetNS(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_GetNSClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Crobat_serviceDesc.Streams[8], "/proto.Crobat/GetNS", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &crobatGetNSClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Crobat_GetNSClient interface {
+	Recv() (*Domain, error)
+	grpc.ClientStream
+}
+
+type crobatGetNSClient struct {
+	grpc.ClientStream
+}
+
+func (x *crobatGetNSClient) Recv() (*Domain, error) {
+	m := new(Domain)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *crobatClient) GetNSDomains(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_GetNSDomainsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Crobat_serviceDesc.Streams[9], "/proto.Crobat/GetNSDomains", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &crobatGetNSDomainsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Crobat_GetNSDomainsClient interface {
+	Recv() (*Domain, error)
+	grpc.ClientStream
+}
+
+type crobatGetNSDomainsClient struct {
+	grpc.ClientStream
+}
+
+func (x *crobatGetNSDomainsClient) Recv() (*Domain, error) {
+	m := new(Domain)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *crobatClient) GetMX(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_GetMXClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Crobat_serviceDesc.Streams[10], "/proto.Crobat/GetMX", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &crobatGetMXClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Crobat_GetMXClient interface {
+	Recv() (*Domain, error)
+	grpc.ClientStream
+}
+
+type crobatGetMXClient struct {
+	grpc.ClientStream
+}
+
+func (x *crobatGetMXClient) Recv() (*Domain, error) {
+	m := new(Domain)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *crobatClient) GetMXDomains(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Crobat_GetMXDomainsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Crobat_serviceDesc.Streams[11], "/proto.Crobat/GetMXDomains", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &crobatGetMXDomainsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Crobat_GetMXDomainsClient interface {
+	Recv() (*Domain, error)
+	grpc.ClientStream
+}
+
+type crobatGetMXDomainsClient struct {
+	grpc.ClientStream
+}
+
+func (x *crobatGetMXDomainsClient) Recv() (*Domain, error) {
+	m := new(Domain)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *crobatClient) CountSubdomains(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*Count, error) {
+	out := new(Count)
+	err := c.cc.Invoke(ctx, "/proto.Crobat/CountSubdomains", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *crobatClient) CountSuffixDomains(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*Count, error) {
+	out := new(Count)
+	err := c.cc.Invoke(ctx, "/proto.Crobat/CountSuffixDomains", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *crobatClient) CountReverseDNS(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*Count, error) {
+	out := new(Count)
+	err := c.cc.Invoke(ctx, "/proto.Crobat/CountReverseDNS", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *crobatClient) Batch(ctx context.Context, opts ...grpc.CallOption) (Crobat_BatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Crobat_serviceDesc.Streams[12], "/proto.Crobat/Batch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &crobatBatchClient{stream}
+	return x, nil
+}
+
+type Crobat_BatchClient interface {
+	Send(*BatchRequest) error
+	Recv() (*BatchResult, error)
+	grpc.ClientStream
+}
+
+type crobatBatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *crobatBatchClient) Send(m *BatchRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *crobatBatchClient) Recv() (*BatchResult, error) {
+	m := new(BatchResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // CrobatServer is the server API for Crobat service.
 // All implementations must embed UnimplementedCrobatServer
 // for forward compatibility
 type CrobatServer interface {
 	GetSubdomains(*QueryRequest, Crobat_GetSubdomainsServer) error
+	// Returns the addresses which a name resolved to
+	Resolve(*QueryRequest, Crobat_ResolveServer) error
 	GetTLDs(*QueryRequest, Crobat_GetTLDsServer) error
+	// Lists the registered domains under a public suffix, such as gov.uk, along
+	// with how many subdomains each has
+	GetSuffixDomains(*QueryRequest, Crobat_GetSuffixDomainsServer) error
 	ReverseDNS(*QueryRequest, Crobat_ReverseDNSServer) error
 	ReverseDNSRange(*QueryRequest, Crobat_ReverseDNSRangeServer) error
+	// Follows the CNAME records for a name, returning each hop in order
+	GetCNAMEChain(*QueryRequest, Crobat_GetCNAMEChainServer) error
+	// Finds the names which are CNAMEs of a target, or of any name beneath it
+	// when the target is a registered domain
+	GetCNAMEAliases(*QueryRequest, Crobat_GetCNAMEAliasesServer) error
+	// Finds the nameservers of a name
+	GetNS(*QueryRequest, Crobat_GetNSServer) error
+	// Finds the names served by a nameserver, or by any nameserver beneath it
+	// when the query is a registered domain
+	GetNSDomains(*QueryRequest, Crobat_GetNSDomainsServer) error
+	// Finds the mail servers of a name
+	GetMX(*QueryRequest, Crobat_GetMXServer) error
+	// Finds the names using a mail server, or any mail server beneath it when
+	// the query is a registered domain
+	GetMXDomains(*QueryRequest, Crobat_GetMXDomainsServer) error
+	// Count the results which GetSubdomains, GetSuffixDomains and ReverseDNS
+	// or ReverseDNSRange would return for the same query
+	CountSubdomains(context.Context, *QueryRequest) (*Count, error)
+	CountSuffixDomains(context.Context, *QueryRequest) (*Count, error)
+	CountReverseDNS(context.Context, *QueryRequest) (*Count, error)
+	// Runs many queries over a single stream. Results are tagged with the query
+	// they belong to, and a query which fails reports its error without ending
+	// the stream
+	Batch(Crobat_BatchServer) error
 	mustEmbedUnimplementedCrobatServer()
 }
 
@@ -177,15 +549,51 @@ type UnimplementedCrobatServer struct {
 func (UnimplementedCrobatServer) GetSubdomains(*QueryRequest, Crobat_GetSubdomainsServer) error {
 	return status.Errorf(codes.Unimplemented, "method GetSubdomains not implemented")
 }
+func (UnimplementedCrobatServer) Resolve(*QueryRequest, Crobat_ResolveServer) error {
+	return status.Errorf(codes.Unimplemented, "method Resolve not implemented")
+}
 func (UnimplementedCrobatServer) GetTLDs(*QueryRequest, Crobat_GetTLDsServer) error {
 	return status.Errorf(codes.Unimplemented, "method GetTLDs not implemented")
 }
+func (UnimplementedCrobatServer) GetSuffixDomains(*QueryRequest, Crobat_GetSuffixDomainsServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetSuffixDomains not implemented")
+}
 func (UnimplementedCrobatServer) ReverseDNS(*QueryRequest, Crobat_ReverseDNSServer) error {
 	return status.Errorf(codes.Unimplemented, "method ReverseDNS not implemented")
 }
 func (UnimplementedCrobatServer) ReverseDNSRange(*QueryRequest, Crobat_ReverseDNSRangeServer) error {
 	return status.Errorf(codes.Unimplemented, "method ReverseDNSRange not implemented")
 }
+func (UnimplementedCrobatServer) GetCNAMEChain(*QueryRequest, Crobat_GetCNAMEChainServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetCNAMEChain not implemented")
+}
+func (UnimplementedCrobatServer) GetCNAMEAliases(*QueryRequest, Crobat_GetCNAMEAliasesServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetCNAMEAliases not implemented")
+}
+func (UnimplementedCrobatServer) GetNS(*QueryRequest, Crobat_GetNSServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetNS not implemented")
+}
+func (UnimplementedCrobatServer) GetNSDomains(*QueryRequest, Crobat_GetNSDomainsServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetNSDomains not implemented")
+}
+func (UnimplementedCrobatServer) GetMX(*QueryRequest, Crobat_GetMXServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetMX not implemented")
+}
+func (UnimplementedCrobatServer) GetMXDomains(*QueryRequest, Crobat_GetMXDomainsServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetMXDomains not implemented")
+}
+func (UnimplementedCrobatServer) CountSubdomains(context.Context, *QueryRequest) (*Count, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CountSubdomains not implemented")
+}
+func (UnimplementedCrobatServer) CountSuffixDomains(context.Context, *QueryRequest) (*Count, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CountSuffixDomains not implemented")
+}
+func (UnimplementedCrobatServer) CountReverseDNS(context.Context, *QueryRequest) (*Count, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CountReverseDNS not implemented")
+}
+func (UnimplementedCrobatServer) Batch(Crobat_BatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Batch not implemented")
+}
 func (UnimplementedCrobatServer) mustEmbedUnimplementedCrobatServer() {}
 
 // UnsafeCrobatServer may be embedded to opt out of forward compatibility for this service.
@@ -220,6 +628,27 @@ func (x *crobatGetSubdomainsServer) Send(m *Domain) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Crobat_Resolve_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CrobatServer).Resolve(m, &crobatResolveServer{stream})
+}
+
+type Crobat_ResolveServer interface {
+	Send(*Domain) error
+	grpc.ServerStream
+}
+
+type crobatResolveServer struct {
+	grpc.ServerStream
+}
+
+func (x *crobatResolveServer) Send(m *Domain) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _Crobat_GetTLDs_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(QueryRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -241,6 +670,27 @@ func (x *crobatGetTLDsServer) Send(m *Domain) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Crobat_GetSuffixDomains_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CrobatServer).GetSuffixDomains(m, &crobatGetSuffixDomainsServer{stream})
+}
+
+type Crobat_GetSuffixDomainsServer interface {
+	Send(*Domain) error
+	grpc.ServerStream
+}
+
+type crobatGetSuffixDomainsServer struct {
+	grpc.ServerStream
+}
+
+func (x *crobatGetSuffixDomainsServer) Send(m *Domain) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _Crobat_ReverseDNS_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(QueryRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -283,21 +733,250 @@ func (x *crobatReverseDNSRangeServer) Send(m *Domain) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Crobat_GetCNAMEChain_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CrobatServer).GetCNAMEChain(m, &crobatGetCNAMEChainServer{stream})
+}
+
+type Crobat_GetCNAMEChainServer interface {
+	Send(*Domain) error
+	grpc.ServerStream
+}
+
+type crobatGetCNAMEChainServer struct {
+	grpc.ServerStream
+}
+
+func (x *crobatGetCNAMEChainServer) Send(m *Domain) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Crobat_GetCNAMEAliases_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CrobatServer).GetCNAMEAliases(m, &crobatGetCNAMEAliasesServer{stream})
+}
+
+type Crobat_GetCNAMEAliasesServer interface {
+	Send(*Domain) error
+	grpc.ServerStream
+}
+
+type crobatGetCNAMEAliasesServer struct {
+	grpc.ServerStream
+}
+
+func (x *crobatGetCNAMEAliasesServer) Send(m *Domain) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Crobat_GetNS_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CrobatServer).GetNS(m, &crobatGetNSServer{stream})
+}
+
+type Crobat_GetNSServer interface {
+	Send(*Domain) error
+	grpc.ServerStream
+}
+
+type crobatGetNSServer struct {
+	grpc.ServerStream
+}
+
+func (x *crobatGetNSServer) Send(m *Domain) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Crobat_GetNSDomains_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CrobatServer).GetNSDomains(m, &crobatGetNSDomainsServer{stream})
+}
+
+type Crobat_GetNSDomainsServer interface {
+	Send(*Domain) error
+	grpc.ServerStream
+}
+
+type crobatGetNSDomainsServer struct {
+	grpc.ServerStream
+}
+
+func (x *crobatGetNSDomainsServer) Send(m *Domain) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Crobat_GetMX_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CrobatServer).GetMX(m, &crobatGetMXServer{stream})
+}
+
+type Crobat_GetMXServer interface {
+	Send(*Domain) error
+	grpc.ServerStream
+}
+
+type crobatGetMXServer struct {
+	grpc.ServerStream
+}
+
+func (x *crobatGetMXServer) Send(m *Domain) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Crobat_GetMXDomains_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CrobatServer).GetMXDomains(m, &crobatGetMXDomainsServer{stream})
+}
+
+type Crobat_GetMXDomainsServer interface {
+	Send(*Domain) error
+	grpc.ServerStream
+}
+
+type crobatGetMXDomainsServer struct {
+	grpc.ServerStream
+}
+
+func (x *crobatGetMXDomainsServer) Send(m *Domain) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Crobat_CountSubdomains_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CrobatServer).CountSubdomains(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Crobat/CountSubdomains",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CrobatServer).CountSubdomains(ctx, req.(*QueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Crobat_CountSuffixDomains_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CrobatServer).CountSuffixDomains(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Crobat/CountSuffixDomains",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CrobatServer).CountSuffixDomains(ctx, req.(*QueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Crobat_CountReverseDNS_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CrobatServer).CountReverseDNS(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Crobat/CountReverseDNS",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CrobatServer).CountReverseDNS(ctx, req.(*QueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Crobat_Batch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CrobatServer).Batch(&crobatBatchServer{stream})
+}
+
+type Crobat_BatchServer interface {
+	Send(*BatchResult) error
+	Recv() (*BatchRequest, error)
+	grpc.ServerStream
+}
+
+type crobatBatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *crobatBatchServer) Send(m *BatchResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *crobatBatchServer) Recv() (*BatchRequest, error) {
+	m := new(BatchRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 var _Crobat_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "proto.Crobat",
 	HandlerType: (*CrobatServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CountSubdomains",
+			Handler:    _Crobat_CountSubdomains_Handler,
+		},
+		{
+			MethodName: "CountSuffixDomains",
+			Handler:    _Crobat_CountSuffixDomains_Handler,
+		},
+		{
+			MethodName: "CountReverseDNS",
+			Handler:    _Crobat_CountReverseDNS_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "GetSubdomains",
 			Handler:       _Crobat_GetSubdomains_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "Resolve",
+			Handler:       _Crobat_Resolve_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "GetTLDs",
 			Handler:       _Crobat_GetTLDs_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "GetSuffixDomains",
+			Handler:       _Crobat_GetSuffixDomains_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "ReverseDNS",
 			Handler:       _Crobat_ReverseDNS_Handler,
@@ -308,6 +987,42 @@ var _Crobat_serviceDesc = grpc.ServiceDesc{
 			Handler:       _Crobat_ReverseDNSRange_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "GetCNAMEChain",
+			Handler:       _Crobat_GetCNAMEChain_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetCNAMEAliases",
+			Handler:       _Crobat_GetCNAMEAliases_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetNS",
+			Handler:       _Crobat_GetNS_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetNSDomains",
+			Handler:       _Crobat_GetNSDomains_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetMX",
+			Handler:       _Crobat_GetMX_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetMXDomains",
+			Handler:       _Crobat_GetMXDomains_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Batch",
+			Handler:       _Crobat_Batch_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "crobat.proto",
 }